@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/shelterkin/shelterkin/db"
+	"github.com/shelterkin/shelterkin/internal/bootstrap"
+	"github.com/shelterkin/shelterkin/internal/config"
+	"github.com/shelterkin/shelterkin/internal/database"
+)
+
+var errCheckFailed = errors.New("self-check failed")
+
+func runCheckCommand(args []string) error {
+	if len(args) > 0 {
+		return errors.New("usage: shelterkin check")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		reportCheck(os.Stdout, "config", "", err)
+		return errCheckFailed
+	}
+	reportCheck(os.Stdout, "config", "loaded", nil)
+
+	return check(context.Background(), cfg, os.Stdout)
+}
+
+// check verifies a deployment without changing it: the database is opened
+// read-only, so a failing check never creates files, runs migrations or
+// stores a first-run salt
+func check(ctx context.Context, cfg *config.Config, out io.Writer) error {
+	sqlDB, err := database.OpenReadOnly(cfg.DatabasePath, database.Options{
+		BusyTimeoutMS: cfg.SQLiteBusyTimeoutMS,
+		CacheSize:     cfg.SQLiteCacheSize,
+		MmapSize:      cfg.SQLiteMmapSize,
+		Synchronous:   cfg.SQLiteSynchronous,
+	})
+	reportCheck(out, "database", "opened "+cfg.DatabasePath+" read-only", err)
+	if err != nil {
+		return errCheckFailed
+	}
+	defer sqlDB.Close()
+
+	failed := false
+	if err := checkMigrations(ctx, sqlDB, out); err != nil {
+		failed = true
+	}
+
+	err = checkEncryption(sqlDB, cfg)
+	reportCheck(out, "encryption key", "decrypts the verification token", err)
+	if err != nil {
+		failed = true
+	}
+
+	if failed {
+		return errCheckFailed
+	}
+	return nil
+}
+
+func checkMigrations(ctx context.Context, sqlDB *sql.DB, out io.Writer) error {
+	statuses, err := database.MigrationStatuses(ctx, sqlDB, db.MigrationsFS, "migrations")
+	if err == nil {
+		pending := 0
+		for _, status := range statuses {
+			if !status.Applied {
+				pending++
+			}
+		}
+		if pending > 0 {
+			err = fmt.Errorf("%d of %d pending, run shelterkin migrate up", pending, len(statuses))
+		}
+	}
+	reportCheck(out, "migrations", fmt.Sprintf("%d applied, none pending", len(statuses)), err)
+	return err
+}
+
+func checkEncryption(sqlDB *sql.DB, cfg *config.Config) error {
+	salt, err := bootstrap.ReadSalt(sqlDB)
+	if err != nil {
+		return err
+	}
+
+	keyring, err := newKeyring(cfg.EncryptionSecret, cfg.EncryptionSecretPrevious, salt)
+	if err != nil {
+		return fmt.Errorf("initializing encryptor: %w", err)
+	}
+
+	return bootstrap.CheckKey(sqlDB, keyring.Master())
+}
+
+func reportCheck(out io.Writer, name, detail string, err error) {
+	if err != nil {
+		fmt.Fprintf(out, "FAIL  %s: %v\n", name, err)
+		return
+	}
+	fmt.Fprintf(out, "PASS  %s: %s\n", name, detail)
+}
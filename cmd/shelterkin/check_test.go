@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shelterkin/shelterkin/internal/bootstrap"
+	"github.com/shelterkin/shelterkin/internal/config"
+	"github.com/shelterkin/shelterkin/internal/database"
+)
+
+const checkTestSecret = "check-test-secret-0123456789"
+
+// initializedCheckDB sets up a database the way a first server start does:
+// migrated, with a salt and verification token stored
+func initializedCheckDB(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	sqlDB, err := database.Open(path, database.Options{})
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	defer sqlDB.Close()
+
+	if err := migrate(context.Background(), sqlDB, []string{"up"}, &bytes.Buffer{}); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	salt, err := bootstrap.EnsureSalt(sqlDB)
+	if err != nil {
+		t.Fatalf("creating salt: %v", err)
+	}
+	keyring, err := newKeyring(checkTestSecret, nil, salt)
+	if err != nil {
+		t.Fatalf("creating encryptor: %v", err)
+	}
+	if err := bootstrap.VerifyKey(sqlDB, keyring.Master()); err != nil {
+		t.Fatalf("storing verification token: %v", err)
+	}
+	return path
+}
+
+func TestCheckPassesOnInitializedDatabase(t *testing.T) {
+	cfg := &config.Config{DatabasePath: initializedCheckDB(t), EncryptionSecret: checkTestSecret}
+
+	var out bytes.Buffer
+	if err := check(context.Background(), cfg, &out); err != nil {
+		t.Fatalf("check: %v\n%s", err, out.String())
+	}
+	if strings.Contains(out.String(), "FAIL") {
+		t.Errorf("expected every check to pass, got:\n%s", out.String())
+	}
+	if got := strings.Count(out.String(), "PASS"); got != 3 {
+		t.Errorf("expected 3 passing checks, got %d:\n%s", got, out.String())
+	}
+}
+
+func TestCheckFailsWithWrongEncryptionSecret(t *testing.T) {
+	cfg := &config.Config{DatabasePath: initializedCheckDB(t), EncryptionSecret: "a-different-secret-0123456789"}
+
+	var out bytes.Buffer
+	err := check(context.Background(), cfg, &out)
+	if !errors.Is(err, errCheckFailed) {
+		t.Fatalf("expected errCheckFailed, got %v", err)
+	}
+	if !strings.Contains(out.String(), "FAIL  encryption key") {
+		t.Errorf("expected the encryption check to fail, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "PASS  migrations") {
+		t.Errorf("expected the migration check to still pass, got:\n%s", out.String())
+	}
+}
+
+func TestCheckPassesWithRotatedSecret(t *testing.T) {
+	cfg := &config.Config{
+		DatabasePath:             initializedCheckDB(t),
+		EncryptionSecret:         "a-different-secret-0123456789",
+		EncryptionSecretPrevious: []string{checkTestSecret},
+	}
+
+	var out bytes.Buffer
+	if err := check(context.Background(), cfg, &out); err != nil {
+		t.Fatalf("expected the previous secret to still decrypt, got %v\n%s", err, out.String())
+	}
+}
+
+func TestCheckFailsOnMissingDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.db")
+	cfg := &config.Config{DatabasePath: path, EncryptionSecret: checkTestSecret}
+
+	var out bytes.Buffer
+	if err := check(context.Background(), cfg, &out); !errors.Is(err, errCheckFailed) {
+		t.Fatalf("expected errCheckFailed, got %v", err)
+	}
+	if !strings.Contains(out.String(), "FAIL  database") {
+		t.Errorf("expected the database check to fail, got:\n%s", out.String())
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected check to leave no database behind, stat err = %v", err)
+	}
+}
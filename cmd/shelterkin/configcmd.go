@@ -0,0 +1,23 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/shelterkin/shelterkin/internal/config"
+)
+
+// runConfigCommand prints the effective config with secrets redacted, so it
+// can be shared in bug reports
+func runConfigCommand(args []string) error {
+	if len(args) > 0 {
+		return errors.New("usage: shelterkin config")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	return cfg.Dump(os.Stdout)
+}
@@ -2,27 +2,48 @@ package main
 
 import (
 	"context"
-	"database/sql"
-	"encoding/base64"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/shelterkin/shelterkin/db"
+	"github.com/shelterkin/shelterkin/internal/bootstrap"
+	"github.com/shelterkin/shelterkin/internal/buildinfo"
+	"github.com/shelterkin/shelterkin/internal/clock"
 	"github.com/shelterkin/shelterkin/internal/config"
 	"github.com/shelterkin/shelterkin/internal/crypto"
 	"github.com/shelterkin/shelterkin/internal/database"
-	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+	"github.com/shelterkin/shelterkin/internal/maintenance"
 	"github.com/shelterkin/shelterkin/internal/server"
+	"github.com/shelterkin/shelterkin/internal/webhook"
 	"github.com/shelterkin/shelterkin/static"
 )
 
-var version = "dev"
-
 func main() {
+	if len(os.Args) > 1 {
+		var command func([]string) error
+		switch os.Args[1] {
+		case "migrate":
+			command = runMigrateCommand
+		case "check":
+			command = runCheckCommand
+		case "config":
+			command = runConfigCommand
+		}
+		if command != nil {
+			if err := command(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	if err := run(); err != nil {
 		slog.Error("fatal error", "error", err)
 		os.Exit(1)
@@ -38,49 +59,114 @@ func run() error {
 	logLevel := parseLogLevel(cfg.LogLevel)
 	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel})))
 
-	slog.Info("starting shelterkin", "version", version, "port", cfg.Port)
+	slog.Info("starting shelterkin", "version", buildinfo.Get().Version, "port", cfg.Port)
+	for _, warning := range cfg.SecretWarnings() {
+		slog.Warn("weak secret, generate a random value", "warning", warning)
+	}
 
-	if err := os.MkdirAll(cfg.DataDir, 0750); err != nil {
+	if err := os.MkdirAll(cfg.DataDir, cfg.DataDirMode); err != nil {
 		return fmt.Errorf("creating data directory: %w", err)
 	}
+	// MkdirAll leaves an existing directory alone, so only warn about it
+	if info, err := os.Stat(cfg.DataDir); err == nil && info.Mode().Perm()&0077 != 0 {
+		slog.Warn("data directory is accessible to other users, consider chmod 700",
+			"path", cfg.DataDir, "mode", fmt.Sprintf("%#o", info.Mode().Perm()))
+	}
 
-	sqlDB, err := database.Open(cfg.DatabasePath)
+	sqlDB, readDB, err := database.OpenPair(cfg.DatabasePath, database.Options{
+		BusyTimeoutMS: cfg.SQLiteBusyTimeoutMS,
+		CacheSize:     cfg.SQLiteCacheSize,
+		MmapSize:      cfg.SQLiteMmapSize,
+		Synchronous:   cfg.SQLiteSynchronous,
+	})
 	if err != nil {
 		return fmt.Errorf("opening database: %w", err)
 	}
 	defer sqlDB.Close()
+	defer readDB.Close()
 
 	if err := database.RunMigrations(sqlDB, db.MigrationsFS, "migrations"); err != nil {
 		return fmt.Errorf("running migrations: %w", err)
 	}
+	if err := database.EnsureSchema(context.Background(), sqlDB, db.MigrationsFS, "migrations"); err != nil {
+		return err
+	}
+
+	if err := database.Optimize(context.Background(), sqlDB); err != nil {
+		return err
+	}
 
-	salt, err := getOrCreateEncryptionSalt(sqlDB)
+	salt, err := bootstrap.EnsureSalt(sqlDB)
 	if err != nil {
 		return fmt.Errorf("initializing encryption salt: %w", err)
 	}
 
-	key := crypto.DeriveKey(cfg.EncryptionSecret, salt)
-	enc, err := crypto.NewEncryptor(key)
+	keyring, err := newKeyring(cfg.EncryptionSecret, cfg.EncryptionSecretPrevious, salt)
 	if err != nil {
 		return fmt.Errorf("initializing encryptor: %w", err)
 	}
+	enc := keyring.Master()
 
 	// derive a separate key for hmac lookups
-	hmacKey := crypto.DeriveKey(cfg.EncryptionSecret+"-hmac", salt)
-	hmac := crypto.NewHMAC(hmacKey)
+	hmacKey := crypto.DeriveKey(cfg.LookupSecret()+"-hmac", salt)
+	hmac := crypto.NewPepperedHMAC(hmacKey, cfg.HMACPepper)
 
-	if err := verifyEncryptionKey(sqlDB, enc); err != nil {
+	if err := bootstrap.VerifyKey(sqlDB, enc); err != nil {
+		if errors.Is(err, bootstrap.ErrSecretChanged) {
+			// already phrased for the operator, don't bury it under a prefix
+			return err
+		}
 		return fmt.Errorf("encryption key verification failed: %w", err)
 	}
 
-	srv := server.New(cfg, sqlDB, enc, hmac, static.FS)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	janitor := maintenance.NewJanitor(sqlDB, clock.Real(), cfg.MaintenanceInterval, cfg.CheckpointInterval, cfg.LoginAttemptRetention)
+	janitorDone := make(chan struct{})
+	go func() {
+		defer close(janitorDone)
+		janitor.Run(ctx)
+	}()
+	// stop the janitor before the deferred sqlDB.Close runs
+	defer func() {
+		cancel()
+		<-janitorDone
+	}()
+
+	// webhooks stay off unless a receiver is configured
+	if cfg.WebhookURL != "" {
+		webhooks := webhook.NewDispatcher(cfg.WebhookURL, cfg.WebhookSecret, clock.Real())
+		webhooksDone := make(chan struct{})
+		go func() {
+			defer close(webhooksDone)
+			webhooks.Run(ctx)
+		}()
+		defer func() {
+			cancel()
+			<-webhooksDone
+		}()
+	}
+
+	srv := server.New(cfg, sqlDB, readDB, enc, hmac, static.FS)
 
 	shutdownCh := make(chan os.Signal, 1)
 	signal.Notify(shutdownCh, syscall.SIGINT, syscall.SIGTERM)
 
 	errCh := make(chan error, 1)
 	go func() {
-		slog.Info("server listening", "addr", fmt.Sprintf("http://localhost:%d", cfg.Port))
+		if network, addr := cfg.Listener(); network == "unix" {
+			slog.Info("server listening", "socket", addr)
+		} else {
+			scheme := "http"
+			if cfg.TLSEnabled() {
+				scheme = "https"
+			}
+			// an empty host binds every interface, localhost is the useful link
+			if strings.HasPrefix(addr, ":") {
+				addr = "localhost" + addr
+			}
+			slog.Info("server listening", "addr", fmt.Sprintf("%s://%s", scheme, addr))
+		}
 		errCh <- srv.Start()
 	}()
 
@@ -89,9 +175,10 @@ func run() error {
 		return fmt.Errorf("server error: %w", err)
 	case sig := <-shutdownCh:
 		slog.Info("shutdown signal received", "signal", sig)
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-		return srv.Shutdown(ctx)
+		cancel()
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer shutdownCancel()
+		return srv.Shutdown(shutdownCtx)
 	}
 }
 
@@ -108,62 +195,12 @@ func parseLogLevel(s string) slog.Level {
 	}
 }
 
-const (
-	configKeyEncryptionSalt = "encryption_salt"
-	configKeyEncryptionTest = "encryption_test_value"
-	encryptionTestPlaintext = "shelterkin-encryption-verify"
-)
-
-func getOrCreateEncryptionSalt(sqlDB *sql.DB) ([]byte, error) {
-	queries := dbgen.New(sqlDB)
-	ctx := context.Background()
-
-	saltB64, err := queries.GetConfig(ctx, configKeyEncryptionSalt)
-	if err == nil {
-		return base64.StdEncoding.DecodeString(saltB64)
-	}
-
-	// first run: generate and store a new salt
-	salt, err := crypto.GenerateSalt()
-	if err != nil {
-		return nil, fmt.Errorf("generating salt: %w", err)
-	}
-
-	encoded := base64.StdEncoding.EncodeToString(salt)
-	if err := queries.SetConfig(ctx, dbgen.SetConfigParams{Key: configKeyEncryptionSalt, Value: encoded}); err != nil {
-		return nil, fmt.Errorf("storing salt: %w", err)
-	}
-
-	slog.Info("generated new encryption salt")
-	return salt, nil
-}
-
-func verifyEncryptionKey(sqlDB *sql.DB, enc *crypto.Encryptor) error {
-	queries := dbgen.New(sqlDB)
-	ctx := context.Background()
-
-	stored, err := queries.GetConfig(ctx, configKeyEncryptionTest)
-	if err != nil {
-		// first run: encrypt the test value and store it
-		encrypted, encErr := enc.Encrypt(encryptionTestPlaintext)
-		if encErr != nil {
-			return fmt.Errorf("encrypting test value: %w", encErr)
-		}
-		if storeErr := queries.SetConfig(ctx, dbgen.SetConfigParams{Key: configKeyEncryptionTest, Value: encrypted}); storeErr != nil {
-			return fmt.Errorf("storing test value: %w", storeErr)
-		}
-		slog.Info("stored encryption verification token")
-		return nil
-	}
-
-	// subsequent runs: verify we can still decrypt
-	decrypted, err := enc.Decrypt(stored)
-	if err != nil {
-		return fmt.Errorf("ENCRYPTION_SECRET appears to have changed — cannot decrypt existing data: %w", err)
+// newKeyring encrypts with secret and still decrypts what the previous
+// secrets wrote
+func newKeyring(secret string, previous []string, salt []byte) (*crypto.Keyring, error) {
+	previousKeys := make([][]byte, len(previous))
+	for i, old := range previous {
+		previousKeys[i] = crypto.DeriveKey(old, salt)
 	}
-	if decrypted != encryptionTestPlaintext {
-		return fmt.Errorf("encryption verification failed: decrypted value does not match expected")
-	}
-
-	return nil
+	return crypto.NewKeyring(crypto.DeriveKey(secret, salt), previousKeys...)
 }
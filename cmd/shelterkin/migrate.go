@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shelterkin/shelterkin/db"
+	"github.com/shelterkin/shelterkin/internal/config"
+	"github.com/shelterkin/shelterkin/internal/database"
+)
+
+const migrateUsage = "usage: shelterkin migrate status|up|down [--yes]"
+
+func runMigrateCommand(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	if err := requireDatabase(cfg.DatabasePath, args); err != nil {
+		return err
+	}
+
+	sqlDB, err := database.Open(cfg.DatabasePath, database.Options{
+		BusyTimeoutMS: cfg.SQLiteBusyTimeoutMS,
+		CacheSize:     cfg.SQLiteCacheSize,
+		MmapSize:      cfg.SQLiteMmapSize,
+		Synchronous:   cfg.SQLiteSynchronous,
+	})
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer sqlDB.Close()
+
+	return migrate(context.Background(), sqlDB, args, os.Stdout)
+}
+
+// requireDatabase refuses status and down against a database that doesn't
+// exist. opening would create an empty one, so a mistyped DATA_DIR would
+// report every migration as pending instead of failing
+func requireDatabase(path string, args []string) error {
+	if len(args) == 0 || (args[0] != "status" && args[0] != "down") {
+		return nil
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("database not found at %s", path)
+	}
+	return nil
+}
+
+// migrate runs one migrate subcommand against sqlDB. down drops tables and
+// columns, so it refuses to run without --yes
+func migrate(ctx context.Context, sqlDB *sql.DB, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "status":
+		statuses, err := database.MigrationStatuses(ctx, sqlDB, db.MigrationsFS, "migrations")
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tMIGRATION\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", status.Version, status.Source, appliedAt)
+		}
+		return tw.Flush()
+
+	case "up":
+		if err := database.RunMigrations(sqlDB, db.MigrationsFS, "migrations"); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "migrations are up to date")
+		return nil
+
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		flags.SetOutput(out)
+		confirmed := flags.Bool("yes", false, "confirm rolling back the latest migration")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if !*confirmed {
+			return errors.New("migrate down can drop data; re-run with --yes to confirm")
+		}
+		version, err := database.RollbackMigration(ctx, sqlDB, db.MigrationsFS, "migrations")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "rolled back migration %d\n", version)
+		return nil
+
+	default:
+		return errors.New(migrateUsage)
+	}
+}
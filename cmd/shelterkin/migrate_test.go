@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shelterkin/shelterkin/db"
+	"github.com/shelterkin/shelterkin/internal/database"
+)
+
+func openMigrateTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	sqlDB, err := database.Open(filepath.Join(t.TempDir(), "test.db"), database.Options{})
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return sqlDB
+}
+
+func TestMigrateStatusReportsAppliedVersions(t *testing.T) {
+	sqlDB := openMigrateTestDB(t)
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	if err := migrate(ctx, sqlDB, []string{"status"}, &out); err != nil {
+		t.Fatalf("status before up: %v", err)
+	}
+	if got := strings.Count(out.String(), "pending"); got != 4 {
+		t.Fatalf("expected 4 pending migrations on a fresh database, got %d:\n%s", got, out.String())
+	}
+
+	if err := migrate(ctx, sqlDB, []string{"up"}, &out); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	out.Reset()
+	if err := migrate(ctx, sqlDB, []string{"status"}, &out); err != nil {
+		t.Fatalf("status after up: %v", err)
+	}
+	if strings.Contains(out.String(), "pending") {
+		t.Errorf("expected every migration applied, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "004_email_changes.sql") {
+		t.Errorf("expected status to list the latest migration, got:\n%s", out.String())
+	}
+}
+
+func TestMigrateDownRevertsLatestMigration(t *testing.T) {
+	sqlDB := openMigrateTestDB(t)
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	if err := migrate(ctx, sqlDB, []string{"up"}, &out); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	if err := migrate(ctx, sqlDB, []string{"down"}, &out); err == nil {
+		t.Fatal("expected down without --yes to be refused")
+	}
+
+	out.Reset()
+	if err := migrate(ctx, sqlDB, []string{"down", "--yes"}, &out); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if !strings.Contains(out.String(), "rolled back migration 4") {
+		t.Errorf("unexpected down output: %s", out.String())
+	}
+
+	statuses, err := database.MigrationStatuses(ctx, sqlDB, db.MigrationsFS, "migrations")
+	if err != nil {
+		t.Fatalf("reading status: %v", err)
+	}
+	for _, status := range statuses {
+		if want := status.Version < 4; status.Applied != want {
+			t.Errorf("migration %d applied = %v, want %v", status.Version, status.Applied, want)
+		}
+	}
+
+	// the reverted migration's table is gone
+	if _, err := sqlDB.Exec("SELECT id FROM email_changes"); err == nil {
+		t.Error("expected email_changes table to be dropped")
+	}
+}
+
+func TestMigrateRequiresExistingDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.db")
+
+	for _, args := range [][]string{{"status"}, {"down", "--yes"}} {
+		err := requireDatabase(path, args)
+		if err == nil || !strings.Contains(err.Error(), "database not found") {
+			t.Errorf("%v: expected database not found, got %v", args, err)
+		}
+	}
+	if err := requireDatabase(path, []string{"up"}); err != nil {
+		t.Errorf("expected up to create a new database, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected no database to be created, stat err = %v", err)
+	}
+
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := requireDatabase(path, []string{"status"}); err != nil {
+		t.Errorf("expected an existing database to be accepted, got %v", err)
+	}
+}
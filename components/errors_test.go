@@ -0,0 +1,44 @@
+package components
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/a-h/templ"
+)
+
+func render(t *testing.T, c templ.Component) string {
+	t.Helper()
+	var b strings.Builder
+	if err := c.Render(context.Background(), &b); err != nil {
+		t.Fatalf("rendering: %v", err)
+	}
+	return b.String()
+}
+
+func TestAlertEscapesMessage(t *testing.T) {
+	html := render(t, Alert("error", `<img src=x onerror="alert(1)">`))
+
+	if !strings.Contains(html, "alert-error") {
+		t.Errorf("expected alert-error class, got %s", html)
+	}
+	if strings.Contains(html, "<img") {
+		t.Errorf("expected message to be escaped, got %s", html)
+	}
+	if strings.Contains(html, "<button") {
+		t.Errorf("plain alert should not be dismissible, got %s", html)
+	}
+}
+
+func TestAlertVariants(t *testing.T) {
+	if html := render(t, Alert("error", "")); html != "" {
+		t.Errorf("expected nothing for an empty message, got %s", html)
+	}
+	if html := render(t, Alert("shouting", "Heads up")); !strings.Contains(html, "alert-info") {
+		t.Errorf("expected unknown level to fall back to info, got %s", html)
+	}
+	if html := render(t, AlertBanner("warning", "Heads up")); !strings.Contains(html, "<button") {
+		t.Errorf("expected banner to be dismissible, got %s", html)
+	}
+}
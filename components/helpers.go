@@ -1,5 +1,12 @@
 package components
 
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/a-h/templ"
+)
+
 func alertClass(level string) string {
 	switch level {
 	case "error":
@@ -14,3 +21,20 @@ func alertClass(level string) string {
 		return "alert-info"
 	}
 }
+
+// ErrorContent is the alert fragment for HTMX requests, which swap it into
+// #alerts, and the full error page otherwise
+func ErrorContent(htmx bool, title, message string) templ.Component {
+	if htmx {
+		return AlertBanner("error", message)
+	}
+	return ErrorPage(title, message)
+}
+
+// htmxConfig passes the request's csp nonce to htmx so the indicator styles
+// and scripts it inserts are allowed
+func htmxConfig(ctx context.Context) string {
+	nonce := templ.GetNonce(ctx)
+	data, _ := json.Marshal(map[string]string{"inlineStyleNonce": nonce, "inlineScriptNonce": nonce})
+	return string(data)
+}
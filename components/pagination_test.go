@@ -0,0 +1,20 @@
+package components
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPagination(t *testing.T) {
+	if html := render(t, Pagination("", "")); html != "" {
+		t.Errorf("expected nothing for a single page, got %s", html)
+	}
+
+	html := render(t, Pagination("", "/members?after=abc"))
+	if !strings.Contains(html, `href="/members?after=abc"`) {
+		t.Errorf("expected next link, got %s", html)
+	}
+	if !strings.Contains(html, "btn-disabled") {
+		t.Errorf("expected previous to be disabled on the first page, got %s", html)
+	}
+}
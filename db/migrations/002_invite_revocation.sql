@@ -0,0 +1,7 @@
+-- +goose Up
+
+ALTER TABLE invites ADD COLUMN revoked_at TEXT;
+
+-- +goose Down
+
+ALTER TABLE invites DROP COLUMN revoked_at;
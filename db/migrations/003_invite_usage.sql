@@ -0,0 +1,9 @@
+-- +goose Up
+
+ALTER TABLE invites ADD COLUMN max_uses INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE invites ADD COLUMN use_count INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+
+ALTER TABLE invites DROP COLUMN use_count;
+ALTER TABLE invites DROP COLUMN max_uses;
@@ -0,0 +1,18 @@
+-- +goose Up
+
+CREATE TABLE email_changes (
+    id             TEXT NOT NULL PRIMARY KEY,
+    user_id        TEXT NOT NULL REFERENCES users(id),
+    household_id   TEXT NOT NULL REFERENCES households(id),
+    new_email_enc  TEXT NOT NULL,
+    new_email_hash TEXT NOT NULL,
+    token_hash     TEXT NOT NULL UNIQUE,
+    expires_at     TEXT NOT NULL,
+    created_at     TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE INDEX idx_email_changes_user ON email_changes(user_id);
+
+-- +goose Down
+
+DROP TABLE email_changes;
@@ -0,0 +1,14 @@
+-- name: CreateEmailChange :exec
+INSERT INTO email_changes (id, user_id, household_id, new_email_enc, new_email_hash, token_hash, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?);
+
+-- name: DeleteEmailChangesByUser :exec
+DELETE FROM email_changes WHERE user_id = ?;
+
+-- name: ConsumeEmailChange :one
+DELETE FROM email_changes
+WHERE token_hash = ? AND expires_at > strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+RETURNING *;
+
+-- name: DeleteExpiredEmailChanges :execrows
+DELETE FROM email_changes WHERE expires_at <= ?;
@@ -14,6 +14,6 @@ WHERE id = ?;
 UPDATE households SET onboarding_progress = ?, updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
 WHERE id = ?;
 
--- name: UpdateHouseholdSettings :exec
+-- name: UpdateHouseholdSettings :execrows
 UPDATE households SET settings = ?, updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
 WHERE id = ?;
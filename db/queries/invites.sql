@@ -1,19 +1,24 @@
 -- name: CreateInvite :one
-INSERT INTO invites (id, household_id, invited_by, email_enc, email_hash, token_hash, role, expires_at)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+INSERT INTO invites (id, household_id, invited_by, email_enc, email_hash, token_hash, role, expires_at, max_uses)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 RETURNING *;
 
 -- name: GetInviteByToken :one
 SELECT * FROM invites
-WHERE token_hash = ? AND accepted_at IS NULL
+WHERE token_hash = ? AND use_count < max_uses AND revoked_at IS NULL
 AND expires_at > strftime('%Y-%m-%dT%H:%M:%SZ', 'now');
 
--- name: AcceptInvite :exec
-UPDATE invites SET accepted_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
-WHERE id = ?;
+-- name: AcceptInvite :execrows
+UPDATE invites SET use_count = use_count + 1,
+    accepted_at = CASE WHEN use_count + 1 >= max_uses THEN strftime('%Y-%m-%dT%H:%M:%SZ', 'now') ELSE accepted_at END
+WHERE id = ? AND use_count < max_uses AND revoked_at IS NULL;
 
 -- name: ListPendingInvitesByHousehold :many
 SELECT * FROM invites
-WHERE household_id = ? AND accepted_at IS NULL
+WHERE household_id = ? AND accepted_at IS NULL AND revoked_at IS NULL
 AND expires_at > strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
 ORDER BY created_at;
+
+-- name: RevokeInvite :execrows
+UPDATE invites SET revoked_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+WHERE id = ? AND household_id = ? AND accepted_at IS NULL AND revoked_at IS NULL;
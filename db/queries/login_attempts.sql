@@ -11,3 +11,31 @@ AND attempted_at > strftime('%Y-%m-%dT%H:%M:%SZ', datetime('now', ?));
 SELECT COUNT(*) FROM login_attempts
 WHERE ip_address = ? AND succeeded = 0
 AND attempted_at > strftime('%Y-%m-%dT%H:%M:%SZ', datetime('now', ?));
+
+-- name: DeleteOldLoginAttempts :execrows
+DELETE FROM login_attempts WHERE attempted_at < ?;
+
+-- name: ListFailedLoginsByHouseholdUser :many
+SELECT u.id AS user_id, u.email_enc, COUNT(*) AS attempts, CAST(MAX(la.attempted_at) AS TEXT) AS last_attempt_at
+FROM login_attempts la
+JOIN users u ON u.email_hash = la.email_hash
+WHERE u.household_id = ? AND u.deleted_at IS NULL
+AND la.succeeded = 0 AND la.attempted_at > ?
+GROUP BY u.id, u.email_enc
+ORDER BY last_attempt_at DESC;
+
+-- name: ListUnknownEmailFailuresByIP :many
+WITH recent AS (
+    SELECT email_hash, ip_address, attempted_at FROM login_attempts
+    WHERE succeeded = 0 AND attempted_at > ?
+)
+SELECT r.ip_address, COUNT(*) AS attempts, CAST(MAX(r.attempted_at) AS TEXT) AS last_attempt_at
+FROM recent r
+WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.email_hash = r.email_hash)
+AND r.ip_address IN (
+    SELECT r2.ip_address FROM recent r2
+    JOIN users u2 ON u2.email_hash = r2.email_hash
+    WHERE u2.household_id = ?
+)
+GROUP BY r.ip_address
+ORDER BY last_attempt_at DESC;
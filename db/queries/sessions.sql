@@ -9,8 +9,8 @@ SELECT * FROM sessions WHERE id = ? AND expires_at > strftime('%Y-%m-%dT%H:%M:%S
 -- name: DeleteSession :exec
 DELETE FROM sessions WHERE id = ?;
 
--- name: DeleteExpiredSessions :exec
-DELETE FROM sessions WHERE expires_at <= strftime('%Y-%m-%dT%H:%M:%SZ', 'now');
+-- name: DeleteExpiredSessions :execrows
+DELETE FROM sessions WHERE expires_at <= ?;
 
 -- name: DeleteSessionsByUser :exec
 DELETE FROM sessions WHERE user_id = ?;
@@ -18,3 +18,6 @@ DELETE FROM sessions WHERE user_id = ?;
 -- name: UpdateSessionLastActive :exec
 UPDATE sessions SET last_active_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
 WHERE id = ?;
+
+-- name: ListSessionsByUser :many
+SELECT * FROM sessions WHERE user_id = ? ORDER BY created_at DESC;
@@ -20,6 +20,17 @@ WHERE id = ?;
 UPDATE users SET role = ?, updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
 WHERE id = ? AND household_id = ?;
 
+-- name: UpdateUserDisplayName :execrows
+UPDATE users SET display_name_enc = ?, updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+WHERE id = ? AND household_id = ? AND deleted_at IS NULL;
+
+-- name: UpdateUserEmail :execrows
+UPDATE users SET email_enc = ?, email_hash = ?, updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+WHERE id = ? AND household_id = ? AND deleted_at IS NULL;
+
+-- name: EmailHashExists :one
+SELECT EXISTS (SELECT 1 FROM users WHERE email_hash = ?);
+
 -- name: SoftDeleteUser :exec
 UPDATE users SET deleted_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
 WHERE id = ? AND household_id = ?;
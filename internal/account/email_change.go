@@ -0,0 +1,119 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/crypto"
+	"github.com/shelterkin/shelterkin/internal/database"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+	"github.com/shelterkin/shelterkin/internal/emailaddr"
+	"github.com/shelterkin/shelterkin/internal/ulid"
+)
+
+const EmailChangeTTL = 24 * time.Hour
+
+// RequestEmailChange records a pending change and returns the token to send
+// to the new address. the account keeps its current email until
+// ConfirmEmailChange, so a hijacked session can't silently move an account
+// to an address the owner doesn't control. a new request replaces any
+// outstanding one
+func (s *Service) RequestEmailChange(ctx context.Context, householdID, userID, newEmail, currentPassword string) (string, error) {
+	user, err := s.loadProfile(ctx, householdID, userID)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkPassword(user.User, currentPassword); err != nil {
+		return "", err
+	}
+
+	newEmail = emailaddr.Normalize(newEmail)
+	if problem := emailaddr.Problem(newEmail); problem != "" {
+		return "", apperror.Validation("email", problem)
+	}
+	newHash := s.hmac.Hash(newEmail)
+	if crypto.ConstantTimeEqual(newHash, user.EmailHash) {
+		return "", apperror.Validation("email", "This is already your email address")
+	}
+	// soft-deleted accounts count too, since they still hold the unique index
+	taken, err := s.queries.EmailHashExists(ctx, newHash)
+	if err != nil {
+		return "", apperror.Internal("checking email", err)
+	}
+	if taken != 0 {
+		return "", apperror.Conflict("An account with this email already exists")
+	}
+
+	token, err := crypto.GenerateToken()
+	if err != nil {
+		return "", apperror.Internal("generating email change token", err)
+	}
+	emailEnc, err := s.enc.Encrypt(newEmail)
+	if err != nil {
+		return "", apperror.Internal("encrypting email", err)
+	}
+
+	if err := s.queries.DeleteEmailChangesByUser(ctx, user.ID); err != nil {
+		return "", apperror.Internal("replacing pending email change", err)
+	}
+	err = s.queries.CreateEmailChange(ctx, dbgen.CreateEmailChangeParams{
+		ID:           ulid.New(),
+		UserID:       user.ID,
+		HouseholdID:  user.HouseholdID,
+		NewEmailEnc:  emailEnc,
+		NewEmailHash: newHash,
+		TokenHash:    s.hmac.Hash(token),
+		ExpiresAt:    time.Now().UTC().Add(EmailChangeTTL).Format(database.TimestampFormat),
+	})
+	if err != nil {
+		return "", apperror.Internal("creating email change", err)
+	}
+	return token, nil
+}
+
+// ConfirmEmailChange applies the change token was issued for. the token is
+// used up even if the address was taken in the meantime
+func (s *Service) ConfirmEmailChange(ctx context.Context, token string) error {
+	change, err := s.queries.ConsumeEmailChange(ctx, s.hmac.Hash(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperror.Validation("token", "This confirmation link is invalid or has expired")
+	}
+	if err != nil {
+		return apperror.Internal("loading email change", err)
+	}
+
+	rows, err := s.queries.UpdateUserEmail(ctx, dbgen.UpdateUserEmailParams{
+		EmailEnc:    change.NewEmailEnc,
+		EmailHash:   change.NewEmailHash,
+		ID:          change.UserID,
+		HouseholdID: change.HouseholdID,
+	})
+	if apperror.IsUniqueConstraintViolation(err) {
+		return apperror.UniqueConflict(err)
+	}
+	if err != nil {
+		return apperror.Internal("changing email", err)
+	}
+	if rows == 0 {
+		return apperror.NotFound("user", change.UserID)
+	}
+	return nil
+}
+
+func (s *Service) checkPassword(user dbgen.User, pw string) error {
+	wrong := apperror.Unauthorized("Your current password is incorrect")
+	if !user.PasswordHash.Valid {
+		return wrong
+	}
+	ok, _, err := s.passwords.Verify(user.PasswordHash.String, pw)
+	if err != nil {
+		return apperror.Internal("verifying password", err)
+	}
+	if !ok {
+		return wrong
+	}
+	return nil
+}
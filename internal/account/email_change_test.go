@@ -0,0 +1,133 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+	"github.com/shelterkin/shelterkin/internal/testutil"
+)
+
+const emailChangePassword = "correct horse battery staple"
+
+func setupEmailChange(t *testing.T) (*Service, *dbgen.Queries) {
+	t.Helper()
+	sqlDB := testutil.NewTestDB(t)
+	enc := testutil.NewTestEncryptor(t)
+	queries := dbgen.New(sqlDB)
+	seedUser(t, queries, enc, "h1", "u1", "ada@example.com", "Ada Lovelace")
+	svc := newTestService(t, sqlDB, enc)
+
+	hash, err := svc.passwords.Hash(emailChangePassword)
+	if err != nil {
+		t.Fatalf("hashing password: %v", err)
+	}
+	if _, err := sqlDB.Exec("UPDATE users SET password_hash = ? WHERE id = 'u1'", hash); err != nil {
+		t.Fatalf("setting password: %v", err)
+	}
+	return svc, queries
+}
+
+func requireEmailChangeError(t *testing.T, err error, want apperror.Type) {
+	t.Helper()
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Type != want {
+		t.Fatalf("expected apperror type %d, got %v", want, err)
+	}
+}
+
+func TestEmailChangeIsPendingUntilConfirmed(t *testing.T) {
+	svc, queries := setupEmailChange(t)
+	ctx := context.Background()
+
+	token, err := svc.RequestEmailChange(ctx, "h1", "u1", " Ada@New.example.com", emailChangePassword)
+	if err != nil {
+		t.Fatalf("requesting change: %v", err)
+	}
+
+	overview, err := svc.GetAccountOverview(ctx, "h1", "u1")
+	if err != nil {
+		t.Fatalf("getting overview: %v", err)
+	}
+	if overview.Email != "ada@example.com" {
+		t.Errorf("expected the email to stay unchanged until confirmed, got %q", overview.Email)
+	}
+
+	if err := svc.ConfirmEmailChange(ctx, token); err != nil {
+		t.Fatalf("confirming change: %v", err)
+	}
+	overview, err = svc.GetAccountOverview(ctx, "h1", "u1")
+	if err != nil {
+		t.Fatalf("getting overview: %v", err)
+	}
+	if overview.Email != "ada@new.example.com" {
+		t.Errorf("expected the confirmed email, got %q", overview.Email)
+	}
+	user, err := queries.GetUserByEmailHash(ctx, svc.hmac.Hash("ada@new.example.com"))
+	if err != nil || user.ID != "u1" {
+		t.Errorf("expected the user to be found by the new email hash, got %v, %v", user.ID, err)
+	}
+
+	err = svc.ConfirmEmailChange(ctx, token)
+	requireEmailChangeError(t, err, apperror.TypeValidation)
+}
+
+func TestEmailChangeReplacesOutstandingRequest(t *testing.T) {
+	svc, _ := setupEmailChange(t)
+	ctx := context.Background()
+
+	first, err := svc.RequestEmailChange(ctx, "h1", "u1", "first@example.com", emailChangePassword)
+	if err != nil {
+		t.Fatalf("requesting change: %v", err)
+	}
+	if _, err := svc.RequestEmailChange(ctx, "h1", "u1", "second@example.com", emailChangePassword); err != nil {
+		t.Fatalf("requesting second change: %v", err)
+	}
+
+	err = svc.ConfirmEmailChange(ctx, first)
+	requireEmailChangeError(t, err, apperror.TypeValidation)
+}
+
+func TestEmailChangeRejectsTakenEmail(t *testing.T) {
+	svc, queries := setupEmailChange(t)
+	ctx := context.Background()
+	if _, err := queries.CreateUser(ctx, dbgen.CreateUserParams{
+		ID: "u2", HouseholdID: "h1", EmailEnc: "enc", EmailHash: svc.hmac.Hash("grace@example.com"),
+		DisplayNameEnc: "enc", Role: "member", AuthProvider: "local", Timezone: "UTC",
+	}); err != nil {
+		t.Fatalf("creating second user: %v", err)
+	}
+
+	_, err := svc.RequestEmailChange(ctx, "h1", "u1", "Grace@example.com", emailChangePassword)
+	requireEmailChangeError(t, err, apperror.TypeConflict)
+}
+
+func TestEmailChangeConflictAtConfirmation(t *testing.T) {
+	svc, queries := setupEmailChange(t)
+	ctx := context.Background()
+
+	token, err := svc.RequestEmailChange(ctx, "h1", "u1", "grace@example.com", emailChangePassword)
+	if err != nil {
+		t.Fatalf("requesting change: %v", err)
+	}
+	// someone else registers the address before the link is clicked
+	if _, err := queries.CreateUser(ctx, dbgen.CreateUserParams{
+		ID: "u2", HouseholdID: "h1", EmailEnc: "enc", EmailHash: svc.hmac.Hash("grace@example.com"),
+		PasswordHash: sql.NullString{}, DisplayNameEnc: "enc", Role: "member", AuthProvider: "local", Timezone: "UTC",
+	}); err != nil {
+		t.Fatalf("creating second user: %v", err)
+	}
+
+	err = svc.ConfirmEmailChange(ctx, token)
+	requireEmailChangeError(t, err, apperror.TypeConflict)
+}
+
+func TestEmailChangeRequiresPassword(t *testing.T) {
+	svc, _ := setupEmailChange(t)
+
+	_, err := svc.RequestEmailChange(context.Background(), "h1", "u1", "new@example.com", "wrong password")
+	requireEmailChangeError(t, err, apperror.TypeUnauthorized)
+}
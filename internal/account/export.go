@@ -0,0 +1,114 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+)
+
+const auditExportPageSize = 500
+
+// UserExport is the takeout document for a single user. it carries decrypted
+// profile fields but never password hashes, totp secrets or email hashes
+type UserExport struct {
+	ExportedAt string          `json:"exported_at"`
+	Profile    ProfileExport   `json:"profile"`
+	Sessions   []SessionExport `json:"sessions"`
+	AuditLog   []AuditExport   `json:"audit_log"`
+}
+
+type ProfileExport struct {
+	ID           string `json:"id"`
+	HouseholdID  string `json:"household_id"`
+	Email        string `json:"email"`
+	DisplayName  string `json:"display_name"`
+	Role         string `json:"role"`
+	AuthProvider string `json:"auth_provider"`
+	Timezone     string `json:"timezone"`
+	LastLoginAt  string `json:"last_login_at,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type SessionExport struct {
+	IPAddress    string `json:"ip_address,omitempty"`
+	UserAgent    string `json:"user_agent,omitempty"`
+	CreatedAt    string `json:"created_at"`
+	ExpiresAt    string `json:"expires_at"`
+	LastActiveAt string `json:"last_active_at"`
+}
+
+type AuditExport struct {
+	Action     string `json:"action"`
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id,omitempty"`
+	IPAddress  string `json:"ip_address,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// ExportUserData gathers everything stored about one user
+func (s *Service) ExportUserData(ctx context.Context, householdID, userID string) (*UserExport, error) {
+	user, err := s.loadProfile(ctx, householdID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &UserExport{
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		Profile: ProfileExport{
+			ID:           user.ID,
+			HouseholdID:  user.HouseholdID,
+			Email:        user.email,
+			DisplayName:  user.displayName,
+			Role:         user.Role,
+			AuthProvider: user.AuthProvider,
+			Timezone:     user.Timezone,
+			LastLoginAt:  user.LastLoginAt.String,
+			CreatedAt:    user.CreatedAt,
+		},
+		Sessions: []SessionExport{},
+		AuditLog: []AuditExport{},
+	}
+
+	sessions, err := s.queries.ListSessionsByUser(ctx, user.ID)
+	if err != nil {
+		return nil, apperror.Internal("listing sessions", err)
+	}
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, SessionExport{
+			IPAddress:    session.IpAddress.String,
+			UserAgent:    session.UserAgent.String,
+			CreatedAt:    session.CreatedAt,
+			ExpiresAt:    session.ExpiresAt,
+			LastActiveAt: session.LastActiveAt,
+		})
+	}
+
+	userRef := sql.NullString{String: user.ID, Valid: true}
+	for offset := int64(0); ; offset += auditExportPageSize {
+		entries, err := s.queries.ListAuditLogByUser(ctx, dbgen.ListAuditLogByUserParams{
+			UserID: userRef,
+			Limit:  auditExportPageSize,
+			Offset: offset,
+		})
+		if err != nil {
+			return nil, apperror.Internal("listing audit log", err)
+		}
+		for _, entry := range entries {
+			export.AuditLog = append(export.AuditLog, AuditExport{
+				Action:     entry.Action,
+				EntityType: entry.EntityType,
+				EntityID:   entry.EntityID.String,
+				IPAddress:  entry.IpAddress.String,
+				CreatedAt:  entry.CreatedAt,
+			})
+		}
+		if len(entries) < auditExportPageSize {
+			break
+		}
+	}
+
+	return export, nil
+}
@@ -0,0 +1,117 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/crypto"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+	"github.com/shelterkin/shelterkin/internal/password"
+	"github.com/shelterkin/shelterkin/internal/testutil"
+)
+
+func seedUser(t *testing.T, queries *dbgen.Queries, enc *crypto.Encryptor, householdID, userID, email, name string) {
+	t.Helper()
+	ctx := context.Background()
+
+	nameEnc, _ := enc.Encrypt("Household " + householdID)
+	if _, err := queries.GetHouseholdByID(ctx, householdID); errors.Is(err, sql.ErrNoRows) {
+		if _, err := queries.CreateHousehold(ctx, dbgen.CreateHouseholdParams{
+			ID: householdID, NameEnc: nameEnc, EncryptionSalt: "salt", OnboardingProgress: "{}", Settings: "{}",
+		}); err != nil {
+			t.Fatalf("creating household: %v", err)
+		}
+	}
+
+	emailEnc, _ := enc.Encrypt(email)
+	displayNameEnc, _ := enc.Encrypt(name)
+	if _, err := queries.CreateUser(ctx, dbgen.CreateUserParams{
+		ID:             userID,
+		HouseholdID:    householdID,
+		EmailEnc:       emailEnc,
+		EmailHash:      "hash-" + userID,
+		PasswordHash:   sql.NullString{String: "$argon2id$secret-password-hash", Valid: true},
+		DisplayNameEnc: displayNameEnc,
+		Role:           "admin",
+		AuthProvider:   "local",
+		Timezone:       "UTC",
+	}); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+}
+
+func newTestService(t *testing.T, sqlDB *sql.DB, enc *crypto.Encryptor) *Service {
+	t.Helper()
+	passwords, err := password.NewHashers(password.AlgorithmArgon2id)
+	if err != nil {
+		t.Fatalf("creating hashers: %v", err)
+	}
+	return NewService(sqlDB, enc, testutil.NewTestHMAC(t), passwords)
+}
+
+func TestExportUserData(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	enc := testutil.NewTestEncryptor(t)
+	queries := dbgen.New(sqlDB)
+	ctx := context.Background()
+
+	seedUser(t, queries, enc, "h1", "u1", "ada@example.com", "Ada Lovelace")
+	seedUser(t, queries, enc, "h1", "u2", "grace@example.com", "Grace Hopper")
+
+	if _, err := queries.CreateSession(ctx, dbgen.CreateSessionParams{
+		ID: "s1", UserID: "u1", HouseholdID: "h1",
+		UserAgent: sql.NullString{String: "Firefox", Valid: true},
+		ExpiresAt: "2099-01-01T00:00:00Z",
+	}); err != nil {
+		t.Fatalf("creating session: %v", err)
+	}
+	if err := queries.CreateAuditLog(ctx, dbgen.CreateAuditLogParams{
+		ID: "a1", HouseholdID: "h1", UserID: sql.NullString{String: "u1", Valid: true},
+		Action: "login", EntityType: "user",
+	}); err != nil {
+		t.Fatalf("creating audit entry: %v", err)
+	}
+
+	export, err := newTestService(t, sqlDB, enc).ExportUserData(ctx, "h1", "u1")
+	if err != nil {
+		t.Fatalf("exporting: %v", err)
+	}
+
+	data, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("marshaling export: %v", err)
+	}
+	doc := string(data)
+
+	for _, want := range []string{"ada@example.com", "Ada Lovelace", "Firefox", `"action":"login"`} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("expected export to contain %q, got %s", want, doc)
+		}
+	}
+	for _, secret := range []string{"argon2id", "hash-u1", "password", "Grace Hopper"} {
+		if strings.Contains(doc, secret) {
+			t.Errorf("export must not contain %q, got %s", secret, doc)
+		}
+	}
+}
+
+func TestExportUserDataScopedToHousehold(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	enc := testutil.NewTestEncryptor(t)
+	queries := dbgen.New(sqlDB)
+
+	seedUser(t, queries, enc, "h1", "u1", "ada@example.com", "Ada Lovelace")
+	seedUser(t, queries, enc, "h2", "u2", "grace@example.com", "Grace Hopper")
+
+	_, err := newTestService(t, sqlDB, enc).ExportUserData(context.Background(), "h2", "u1")
+
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Type != apperror.TypeNotFound {
+		t.Fatalf("expected not found for a user in another household, got %v", err)
+	}
+}
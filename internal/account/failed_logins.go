@@ -0,0 +1,81 @@
+package account
+
+import (
+	"context"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/database"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+	"github.com/shelterkin/shelterkin/internal/logsafe"
+	"github.com/shelterkin/shelterkin/internal/role"
+)
+
+type FailedLogins struct {
+	ByUser []FailedLoginsForUser
+	// ByIP covers attempts against emails with no account, which can't be
+	// tied to a household. only ips that also failed against this
+	// household's users are included, so one household never sees another's
+	// typos or the probing aimed at it
+	ByIP []FailedLoginsFromIP
+}
+
+type FailedLoginsForUser struct {
+	UserID string
+	// Email is masked, the admin only needs to recognize the account
+	Email         string
+	Attempts      int64
+	LastAttemptAt string
+}
+
+type FailedLoginsFromIP struct {
+	IPAddress     string
+	Attempts      int64
+	LastAttemptAt string
+}
+
+// ListRecentFailedLogins summarizes failed logins within window for an admin
+// of actingUser's household
+func (s *Service) ListRecentFailedLogins(ctx context.Context, actingUser dbgen.User, window time.Duration) (*FailedLogins, error) {
+	if !role.AtLeast(actingUser.Role, role.Admin) {
+		return nil, apperror.Forbidden("Only admins can review failed logins")
+	}
+	since := time.Now().UTC().Add(-window).Format(database.TimestampFormat)
+
+	users, err := s.queries.ListFailedLoginsByHouseholdUser(ctx, dbgen.ListFailedLoginsByHouseholdUserParams{
+		HouseholdID: actingUser.HouseholdID,
+		AttemptedAt: since,
+	})
+	if err != nil {
+		return nil, apperror.Internal("listing failed logins by user", err)
+	}
+	ips, err := s.queries.ListUnknownEmailFailuresByIP(ctx, dbgen.ListUnknownEmailFailuresByIPParams{
+		AttemptedAt: since,
+		HouseholdID: actingUser.HouseholdID,
+	})
+	if err != nil {
+		return nil, apperror.Internal("listing failed logins by ip", err)
+	}
+
+	result := &FailedLogins{ByUser: []FailedLoginsForUser{}, ByIP: []FailedLoginsFromIP{}}
+	for _, row := range users {
+		email, err := s.enc.Decrypt(row.EmailEnc)
+		if err != nil {
+			return nil, apperror.Internal("decrypting email", err)
+		}
+		result.ByUser = append(result.ByUser, FailedLoginsForUser{
+			UserID:        row.UserID,
+			Email:         logsafe.Email(email),
+			Attempts:      row.Attempts,
+			LastAttemptAt: row.LastAttemptAt,
+		})
+	}
+	for _, row := range ips {
+		result.ByIP = append(result.ByIP, FailedLoginsFromIP{
+			IPAddress:     row.IpAddress,
+			Attempts:      row.Attempts,
+			LastAttemptAt: row.LastAttemptAt,
+		})
+	}
+	return result, nil
+}
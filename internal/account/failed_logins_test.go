@@ -0,0 +1,89 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/testutil"
+)
+
+func TestListRecentFailedLoginsScopedToHousehold(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	svc := newTestService(t, sqlDB, testutil.NewTestEncryptor(t))
+	ctx := context.Background()
+
+	mine := testutil.CreateTestHousehold(t, sqlDB)
+	theirs := testutil.CreateTestHousehold(t, sqlDB)
+	admin := testutil.CreateTestUser(t, sqlDB, mine.ID, testutil.WithRole("admin"), testutil.WithEmail("ada@example.com"))
+	other := testutil.CreateTestUser(t, sqlDB, theirs.ID, testutil.WithEmail("grace@example.com"))
+
+	for i := 0; i < 3; i++ {
+		testutil.CreateTestLoginAttempt(t, sqlDB, admin.EmailHash, testutil.WithAttemptIP("203.0.113.7"))
+	}
+	testutil.CreateTestLoginAttempt(t, sqlDB, admin.EmailHash, testutil.WithAttemptSucceeded(true))
+	testutil.CreateTestLoginAttempt(t, sqlDB, admin.EmailHash, testutil.WithAttemptedAt(time.Now().Add(-48*time.Hour)))
+	testutil.CreateTestLoginAttempt(t, sqlDB, other.EmailHash, testutil.WithAttemptIP("198.51.100.9"))
+
+	got, err := svc.ListRecentFailedLogins(ctx, admin, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("listing: %v", err)
+	}
+	if len(got.ByUser) != 1 {
+		t.Fatalf("expected only this household's user, got %+v", got.ByUser)
+	}
+	if row := got.ByUser[0]; row.UserID != admin.ID || row.Attempts != 3 || row.Email != "a***@example.com" || row.LastAttemptAt == "" {
+		t.Errorf("expected 3 recent failures for the masked admin email, got %+v", row)
+	}
+
+	// the other household's attempt must not surface in any form
+	for _, row := range got.ByIP {
+		if row.IPAddress == "198.51.100.9" {
+			t.Errorf("another household's attempt leaked: %+v", row)
+		}
+	}
+}
+
+func TestListRecentFailedLoginsAggregatesUnknownEmailsByIP(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	hmac := testutil.NewTestHMAC(t)
+	svc := newTestService(t, sqlDB, testutil.NewTestEncryptor(t))
+
+	household := testutil.CreateTestHousehold(t, sqlDB)
+	admin := testutil.CreateTestUser(t, sqlDB, household.ID, testutil.WithRole("admin"))
+
+	// an attacker hits a real account once, then sprays unknown addresses
+	testutil.CreateTestLoginAttempt(t, sqlDB, admin.EmailHash, testutil.WithAttemptIP("203.0.113.7"))
+	for _, email := range []string{"nobody@example.com", "root@example.com", "test@example.com"} {
+		testutil.CreateTestLoginAttempt(t, sqlDB, hmac.Hash(email), testutil.WithAttemptIP("203.0.113.7"))
+	}
+	// probing from an ip that never touched this household stays invisible
+	testutil.CreateTestLoginAttempt(t, sqlDB, hmac.Hash("someone@example.com"), testutil.WithAttemptIP("198.51.100.9"))
+
+	got, err := svc.ListRecentFailedLogins(context.Background(), admin, time.Hour)
+	if err != nil {
+		t.Fatalf("listing: %v", err)
+	}
+	if len(got.ByIP) != 1 {
+		t.Fatalf("expected one aggregated ip, got %+v", got.ByIP)
+	}
+	if row := got.ByIP[0]; row.IPAddress != "203.0.113.7" || row.Attempts != 3 {
+		t.Errorf("expected 3 unknown-email failures from the attacking ip, got %+v", row)
+	}
+}
+
+func TestListRecentFailedLoginsAdminOnly(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	svc := newTestService(t, sqlDB, testutil.NewTestEncryptor(t))
+
+	household := testutil.CreateTestHousehold(t, sqlDB)
+	member := testutil.CreateTestUser(t, sqlDB, household.ID)
+
+	_, err := svc.ListRecentFailedLogins(context.Background(), member, time.Hour)
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Type != apperror.TypeForbidden {
+		t.Fatalf("expected forbidden for a member, got %v", err)
+	}
+}
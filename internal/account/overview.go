@@ -0,0 +1,57 @@
+package account
+
+import (
+	"context"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/useragent"
+)
+
+type Overview struct {
+	DisplayName string
+	Email       string
+	LastLoginAt string
+	LastLoginIP string
+	Sessions    []SessionSummary
+}
+
+// SessionSummary is a session as the active-sessions list shows it, with the
+// raw user agent and ip reduced to labels
+type SessionSummary struct {
+	Device       string
+	Network      string
+	CreatedAt    string
+	LastActiveAt string
+}
+
+// GetAccountOverview summarizes the caller's own account. the last login ip
+// comes from their newest session since login attempts are keyed by email hash
+func (s *Service) GetAccountOverview(ctx context.Context, householdID, userID string) (*Overview, error) {
+	user, err := s.loadProfile(ctx, householdID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	overview := &Overview{
+		DisplayName: user.displayName,
+		Email:       user.email,
+		LastLoginAt: user.LastLoginAt.String,
+	}
+
+	sessions, err := s.queries.ListSessionsByUser(ctx, user.ID)
+	if err != nil {
+		return nil, apperror.Internal("listing sessions", err)
+	}
+	if len(sessions) > 0 {
+		overview.LastLoginIP = sessions[0].IpAddress.String
+	}
+	for _, session := range sessions {
+		overview.Sessions = append(overview.Sessions, SessionSummary{
+			Device:       useragent.Label(session.UserAgent.String),
+			Network:      useragent.Network(session.IpAddress.String),
+			CreatedAt:    session.CreatedAt,
+			LastActiveAt: session.LastActiveAt,
+		})
+	}
+	return overview, nil
+}
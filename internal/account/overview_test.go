@@ -0,0 +1,68 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+	"github.com/shelterkin/shelterkin/internal/testutil"
+)
+
+func TestGetAccountOverviewReflectsRecentLogin(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	enc := testutil.NewTestEncryptor(t)
+	queries := dbgen.New(sqlDB)
+	ctx := context.Background()
+
+	seedUser(t, queries, enc, "h1", "u1", "ada@example.com", "Ada Lovelace")
+	if err := queries.UpdateUserLastLogin(ctx, "u1"); err != nil {
+		t.Fatalf("recording login: %v", err)
+	}
+	if _, err := queries.CreateSession(ctx, dbgen.CreateSessionParams{
+		ID: "s1", UserID: "u1", HouseholdID: "h1",
+		IpAddress: sql.NullString{String: "203.0.113.7", Valid: true},
+		UserAgent: sql.NullString{String: "Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0", Valid: true},
+		ExpiresAt: "2099-01-01T00:00:00Z",
+	}); err != nil {
+		t.Fatalf("creating session: %v", err)
+	}
+
+	overview, err := newTestService(t, sqlDB, enc).GetAccountOverview(ctx, "h1", "u1")
+	if err != nil {
+		t.Fatalf("getting overview: %v", err)
+	}
+	if overview.DisplayName != "Ada Lovelace" || overview.Email != "ada@example.com" {
+		t.Errorf("expected decrypted profile, got %+v", overview)
+	}
+	if overview.LastLoginAt == "" {
+		t.Error("expected last login time")
+	}
+	if overview.LastLoginIP != "203.0.113.7" {
+		t.Errorf("expected last login ip, got %q", overview.LastLoginIP)
+	}
+	if len(overview.Sessions) != 1 {
+		t.Fatalf("expected one session, got %d", len(overview.Sessions))
+	}
+	if got := overview.Sessions[0]; got.Device != "Firefox on Linux" || got.Network != "IPv4 203.x.x.x" {
+		t.Errorf("expected labelled session, got %+v", got)
+	}
+}
+
+func TestGetAccountOverviewScopedToCaller(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	enc := testutil.NewTestEncryptor(t)
+	queries := dbgen.New(sqlDB)
+
+	seedUser(t, queries, enc, "h1", "u1", "ada@example.com", "Ada Lovelace")
+	seedUser(t, queries, enc, "h2", "u2", "grace@example.com", "Grace Hopper")
+
+	_, err := newTestService(t, sqlDB, enc).GetAccountOverview(context.Background(), "h2", "u1")
+
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Type != apperror.TypeNotFound {
+		t.Fatalf("expected not found outside the caller's household, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package account
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+	"golang.org/x/text/unicode/norm"
+)
+
+const maxDisplayNameLength = 100
+
+// UpdateDisplayName changes the caller's own display name, scoped to
+// householdID like loadProfile
+func (s *Service) UpdateDisplayName(ctx context.Context, householdID, userID, name string) error {
+	name, err := normalizeDisplayName(name)
+	if err != nil {
+		return err
+	}
+
+	nameEnc, err := s.enc.Encrypt(name)
+	if err != nil {
+		return apperror.Internal("encrypting display name", err)
+	}
+	rows, err := s.queries.UpdateUserDisplayName(ctx, dbgen.UpdateUserDisplayNameParams{
+		DisplayNameEnc: nameEnc,
+		ID:             userID,
+		HouseholdID:    householdID,
+	})
+	if err != nil {
+		return apperror.Internal("saving display name", err)
+	}
+	if rows == 0 {
+		return apperror.NotFound("user", userID)
+	}
+	return nil
+}
+
+// normalizeDisplayName composes unicode and collapses runs of whitespace,
+// so names that look the same are stored the same
+func normalizeDisplayName(name string) (string, error) {
+	name = strings.Join(strings.Fields(norm.NFC.String(name)), " ")
+	switch {
+	case name == "":
+		return "", apperror.Validation("display_name", "Enter a display name")
+	case utf8.RuneCountInString(name) > maxDisplayNameLength:
+		return "", apperror.Validation("display_name", "Display name must be 100 characters or fewer")
+	case strings.ContainsFunc(name, unicode.IsControl):
+		return "", apperror.Validation("display_name", "Display name contains characters that can't be shown")
+	}
+	return name, nil
+}
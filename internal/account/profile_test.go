@@ -0,0 +1,67 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+	"github.com/shelterkin/shelterkin/internal/testutil"
+)
+
+func TestUpdateDisplayName(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	enc := testutil.NewTestEncryptor(t)
+	seedUser(t, dbgen.New(sqlDB), enc, "h1", "u1", "ada@example.com", "Ada Lovelace")
+	svc := newTestService(t, sqlDB, enc)
+	ctx := context.Background()
+
+	if err := svc.UpdateDisplayName(ctx, "h1", "u1", "  Ada   King \t"); err != nil {
+		t.Fatalf("updating display name: %v", err)
+	}
+
+	overview, err := svc.GetAccountOverview(ctx, "h1", "u1")
+	if err != nil {
+		t.Fatalf("getting overview: %v", err)
+	}
+	if overview.DisplayName != "Ada King" {
+		t.Errorf("expected the normalized new name, got %q", overview.DisplayName)
+	}
+}
+
+func TestUpdateDisplayNameValidation(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	enc := testutil.NewTestEncryptor(t)
+	seedUser(t, dbgen.New(sqlDB), enc, "h1", "u1", "ada@example.com", "Ada Lovelace")
+	svc := newTestService(t, sqlDB, enc)
+	ctx := context.Background()
+
+	for _, name := range []string{"", "   ", strings.Repeat("a", maxDisplayNameLength+1), "Ada\x00"} {
+		var appErr *apperror.Error
+		if err := svc.UpdateDisplayName(ctx, "h1", "u1", name); !errors.As(err, &appErr) || appErr.Type != apperror.TypeValidation {
+			t.Errorf("name %q: expected a validation error, got %v", name, err)
+		}
+	}
+
+	overview, err := svc.GetAccountOverview(ctx, "h1", "u1")
+	if err != nil {
+		t.Fatalf("getting overview: %v", err)
+	}
+	if overview.DisplayName != "Ada Lovelace" {
+		t.Errorf("expected a rejected name to leave the old one, got %q", overview.DisplayName)
+	}
+}
+
+func TestUpdateDisplayNameScopedToHousehold(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	enc := testutil.NewTestEncryptor(t)
+	seedUser(t, dbgen.New(sqlDB), enc, "h1", "u1", "ada@example.com", "Ada Lovelace")
+
+	err := newTestService(t, sqlDB, enc).UpdateDisplayName(context.Background(), "h2", "u1", "Mallory")
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Type != apperror.TypeNotFound {
+		t.Errorf("expected not found for another household, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/crypto"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+	"github.com/shelterkin/shelterkin/internal/password"
+)
+
+type Service struct {
+	queries   *dbgen.Queries
+	enc       *crypto.Encryptor
+	hmac      *crypto.HMACHasher
+	passwords *password.Hashers
+}
+
+func NewService(db *sql.DB, enc *crypto.Encryptor, hmac *crypto.HMACHasher, passwords *password.Hashers) *Service {
+	return &Service{queries: dbgen.New(db), enc: enc, hmac: hmac, passwords: passwords}
+}
+
+type profile struct {
+	dbgen.User
+	email       string
+	displayName string
+}
+
+// loadProfile looks the user up within householdID only, so callers passing
+// ids from the session can never read another household's account
+func (s *Service) loadProfile(ctx context.Context, householdID, userID string) (profile, error) {
+	user, err := s.queries.GetUserByID(ctx, dbgen.GetUserByIDParams{ID: userID, HouseholdID: householdID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return profile{}, apperror.NotFound("user", userID)
+	}
+	if err != nil {
+		return profile{}, apperror.Internal("loading user", err)
+	}
+
+	email, err := s.enc.Decrypt(user.EmailEnc)
+	if err != nil {
+		return profile{}, apperror.Internal("decrypting email", err)
+	}
+	displayName, err := s.enc.Decrypt(user.DisplayNameEnc)
+	if err != nil {
+		return profile{}, apperror.Internal("decrypting display name", err)
+	}
+	return profile{User: user, email: email, displayName: displayName}, nil
+}
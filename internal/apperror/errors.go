@@ -2,8 +2,12 @@ package apperror
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
+
+	"github.com/shelterkin/shelterkin/internal/logsafe"
 )
 
 type Type int
@@ -23,27 +27,47 @@ type Error struct {
 	Type       Type
 	Message    string
 	Field      string
+	Resource   string
 	RetryAfter time.Duration
+	Fields     []FieldError
 	Err        error
 }
 
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// Error is what ends up in logs, so emails and tokens that reach it through
+// a wrapped driver or parse error are masked
 func (e *Error) Error() string {
 	if e.Err != nil {
-		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+		return logsafe.Redact(fmt.Sprintf("%s: %v", e.Message, e.Err))
 	}
-	return e.Message
+	return logsafe.Redact(e.Message)
 }
 
 func (e *Error) Unwrap() error {
 	return e.Err
 }
 
+// LogAttrs returns slog key/value pairs for server-side logging, including
+// details such as the resource id that are never shown to the user
+func (e *Error) LogAttrs() []any {
+	attrs := []any{"error", e.Error()}
+	if e.Resource != "" {
+		attrs = append(attrs, "resource", e.Resource)
+	}
+	return attrs
+}
+
 func Validation(field, message string) *Error {
 	return &Error{Type: TypeValidation, Message: message, Field: field}
 }
 
+// NotFound keeps the id on Resource for logging; the message stays generic
 func NotFound(entity, id string) *Error {
-	return &Error{Type: TypeNotFound, Message: fmt.Sprintf("%s not found", entity)}
+	return &Error{Type: TypeNotFound, Message: fmt.Sprintf("%s not found", entity), Resource: id}
 }
 
 func Unauthorized(message string) *Error {
@@ -74,6 +98,15 @@ func Unavailable(message string) *Error {
 	return &Error{Type: TypeUnavailable, Message: message}
 }
 
+// ApplyHeaders sets the headers that go with err. call it before WriteHeader
+// on every path that renders an *Error, whether json or html
+func ApplyHeaders(w http.ResponseWriter, err *Error) {
+	if err.Type == TypeRateLimited && err.RetryAfter > 0 {
+		seconds := int(math.Ceil(err.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+}
+
 func HTTPStatus(err *Error) int {
 	switch err.Type {
 	case TypeValidation:
@@ -1,8 +1,11 @@
 package apperror
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -33,6 +36,21 @@ func TestNotFoundError(t *testing.T) {
 	}
 }
 
+func TestNotFoundRetainsResourceID(t *testing.T) {
+	err := NotFound("medication", "abc123")
+	if err.Resource != "abc123" {
+		t.Errorf("expected Resource 'abc123', got %q", err.Resource)
+	}
+	if err.Error() != "medication not found" {
+		t.Errorf("expected Error() to omit the id, got %q", err.Error())
+	}
+
+	attrs := err.LogAttrs()
+	if len(attrs) != 4 || attrs[2] != "resource" || attrs[3] != "abc123" {
+		t.Errorf("expected resource in log attrs, got %v", attrs)
+	}
+}
+
 func TestInternalErrorWraps(t *testing.T) {
 	underlying := errors.New("connection refused")
 	err := Internal("Failed to save", underlying)
@@ -101,6 +119,90 @@ func TestValidationErrors(t *testing.T) {
 	}
 }
 
+func TestValidationErrorsToMap(t *testing.T) {
+	ve := &ValidationErrors{}
+	ve.Add("name", "Name is required")
+	ve.Add("email", "Email is required")
+	ve.Add("email", "Email is invalid")
+
+	m := ve.ToMap()
+	if len(m) != 2 {
+		t.Fatalf("expected 2 fields, got %d", len(m))
+	}
+	if m["email"] != "Email is required" {
+		t.Errorf("expected first email message to win, got %q", m["email"])
+	}
+}
+
+func TestValidationErrorsToError(t *testing.T) {
+	ve := &ValidationErrors{}
+	if ve.ToError() != nil {
+		t.Error("expected nil error when nothing was added")
+	}
+
+	ve.Add("name", "Name is required")
+	ve.Add("email", "Email is required")
+
+	err := ve.ToError()
+	if err.Type != TypeValidation {
+		t.Errorf("expected TypeValidation, got %d", err.Type)
+	}
+	if err.Field != "name" {
+		t.Errorf("expected first field 'name', got %q", err.Field)
+	}
+	if err.Message != "Validation failed" {
+		t.Errorf("expected generic message, got %q", err.Message)
+	}
+	if len(err.Fields) != 2 {
+		t.Fatalf("expected 2 field errors, got %d", len(err.Fields))
+	}
+	if err.Fields[1].Field != "email" || err.Fields[1].Message != "Email is required" {
+		t.Errorf("unexpected second field error: %+v", err.Fields[1])
+	}
+}
+
+func TestWriteJSONIncludesFields(t *testing.T) {
+	ve := &ValidationErrors{}
+	ve.Add("name", "Name is required")
+	ve.Add("email", "Email is required")
+
+	rec := httptest.NewRecorder()
+	WriteJSON(rec, ve.ToError())
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json, got %q", ct)
+	}
+
+	var body struct {
+		Error  string            `json:"error"`
+		Fields map[string]string `json:"fields"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if body.Error != "Validation failed" {
+		t.Errorf("expected generic error message, got %q", body.Error)
+	}
+	if body.Fields["name"] != "Name is required" || body.Fields["email"] != "Email is required" {
+		t.Errorf("unexpected fields: %v", body.Fields)
+	}
+}
+
+func TestWriteJSONOmitsWrappedError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteJSON(rec, Internal("Failed to save", errors.New("no such table: users")))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); body != "{\"error\":\"Failed to save\"}\n" {
+		t.Errorf("unexpected body: %q", body)
+	}
+}
+
 func TestIsUniqueConstraintViolation(t *testing.T) {
 	if IsUniqueConstraintViolation(nil) {
 		t.Error("expected false for nil error")
@@ -125,4 +227,71 @@ func TestParseConstraintColumn(t *testing.T) {
 	if col != "email_hash" {
 		t.Errorf("expected 'email_hash', got %q", col)
 	}
+
+	// modernc's wording, with the extended result code on the end
+	err = errors.New("constraint failed: UNIQUE constraint failed: users.email_hash (2067)")
+	if col := ParseConstraintColumn(err); col != "email_hash" {
+		t.Errorf("expected 'email_hash' without the result code, got %q", col)
+	}
+}
+
+func TestUniqueConflictTagsField(t *testing.T) {
+	err := UniqueConflict(errors.New("constraint failed: UNIQUE constraint failed: users.email_hash (2067)"))
+	if err.Type != TypeConflict || err.Field != "email" {
+		t.Fatalf("expected conflict on the email field, got type %d field %q", err.Type, err.Field)
+	}
+
+	rec := httptest.NewRecorder()
+	WriteJSON(rec, err)
+	if !strings.Contains(rec.Body.String(), `"email":"An account with this email already exists"`) {
+		t.Errorf("expected field error in response, got %s", rec.Body.String())
+	}
+}
+
+func TestUniqueConflictFallsBackToGeneric(t *testing.T) {
+	for _, cause := range []error{
+		errors.New("UNIQUE constraint failed: invites.token_hash"),
+		errors.New("database is locked"),
+	} {
+		err := UniqueConflict(cause)
+		if err.Type != TypeConflict || err.Field != "" || err.Message != "This already exists" {
+			t.Errorf("expected generic conflict for %v, got %+v", cause, err)
+		}
+	}
+}
+
+func TestErrorRedactsEmail(t *testing.T) {
+	err := Internal("loading user", errors.New("no row for ada.lovelace@example.com"))
+
+	got := err.Error()
+	if strings.Contains(got, "ada.lovelace") {
+		t.Fatalf("expected email to be redacted, got %q", got)
+	}
+	if got != "loading user: no row for a***@example.com" {
+		t.Errorf("unexpected error string %q", got)
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	err := RateLimited("Too many requests", 1500*time.Millisecond)
+
+	jsonRec := httptest.NewRecorder()
+	WriteJSON(jsonRec, err)
+	if got := jsonRec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("json path: expected Retry-After 2, got %q", got)
+	}
+
+	// html renderers call ApplyHeaders themselves before writing the page
+	htmlRec := httptest.NewRecorder()
+	ApplyHeaders(htmlRec, err)
+	htmlRec.WriteHeader(HTTPStatus(err))
+	if got := htmlRec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("html path: expected Retry-After 2, got %q", got)
+	}
+
+	other := httptest.NewRecorder()
+	WriteJSON(other, Conflict("Already exists"))
+	if got := other.Header().Get("Retry-After"); got != "" {
+		t.Errorf("expected no Retry-After for a conflict, got %q", got)
+	}
 }
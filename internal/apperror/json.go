@@ -0,0 +1,27 @@
+package apperror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type jsonResponse struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// WriteJSON writes the user-facing message and any field errors with the
+// matching status code; the wrapped error never leaves the server
+func WriteJSON(w http.ResponseWriter, err *Error) {
+	resp := jsonResponse{Error: err.Message}
+	if len(err.Fields) > 0 {
+		resp.Fields = fieldsToMap(err.Fields)
+	} else if err.Field != "" {
+		resp.Fields = map[string]string{err.Field: err.Message}
+	}
+
+	ApplyHeaders(w, err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(HTTPStatus(err))
+	json.NewEncoder(w).Encode(resp)
+}
@@ -21,9 +21,29 @@ func ParseConstraintColumn(err error) string {
 		return ""
 	}
 	column := msg[idx+len(prefix):]
+	// modernc appends the result code, e.g. "users.email_hash (2067)"
+	if end := strings.IndexAny(column, " ,"); end != -1 {
+		column = column[:end]
+	}
 	// format is "table.column", extract the column part
 	if dotIdx := strings.LastIndex(column, "."); dotIdx != -1 {
 		return column[dotIdx+1:]
 	}
 	return column
 }
+
+// uniqueFields maps columns with a unique index to the form field the UI
+// should highlight when a submitted value collides
+var uniqueFields = map[string]FieldError{
+	"email_hash": {Field: "email", Message: "An account with this email already exists"},
+}
+
+// UniqueConflict turns a unique violation into a Conflict tagged with the
+// form field for the violated column. columns without a form field get the
+// generic message
+func UniqueConflict(err error) *Error {
+	if field, ok := uniqueFields[ParseConstraintColumn(err)]; ok {
+		return &Error{Type: TypeConflict, Message: field.Message, Field: field.Field, Err: err}
+	}
+	return ConflictWithErr("This already exists", err)
+}
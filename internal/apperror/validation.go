@@ -21,3 +21,40 @@ func (ve *ValidationErrors) Error() string {
 	}
 	return strings.Join(messages, "; ")
 }
+
+// ToMap returns the first message recorded for each field
+func (ve *ValidationErrors) ToMap() map[string]string {
+	return fieldsToMap(ve.fields())
+}
+
+// ToError collapses the collected errors into one validation error that still
+// carries every field, or returns nil when nothing was added
+func (ve *ValidationErrors) ToError() *Error {
+	if !ve.HasErrors() {
+		return nil
+	}
+	return &Error{
+		Type:    TypeValidation,
+		Message: "Validation failed",
+		Field:   ve.Errors[0].Field,
+		Fields:  ve.fields(),
+	}
+}
+
+func (ve *ValidationErrors) fields() []FieldError {
+	fields := make([]FieldError, len(ve.Errors))
+	for i, e := range ve.Errors {
+		fields[i] = FieldError{Field: e.Field, Message: e.Message}
+	}
+	return fields
+}
+
+func fieldsToMap(fields []FieldError) map[string]string {
+	m := make(map[string]string, len(fields))
+	for _, f := range fields {
+		if _, exists := m[f.Field]; !exists {
+			m[f.Field] = f.Message
+		}
+	}
+	return m
+}
@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/ulid"
+)
+
+// Event is one audit_log row. empty optional fields are stored as NULL
+type Event struct {
+	HouseholdID string
+	UserID      string
+	Action      string
+	EntityType  string
+	EntityID    string
+	Metadata    string
+	IPAddress   string
+}
+
+const (
+	// each row binds eight parameters, well under sqlite's variable limit
+	maxBatchSize = 100
+	// bounds how long Run spends flushing what is left at shutdown
+	finalFlushTimeout = 5 * time.Second
+)
+
+// Writer queues audit events and inserts them in batches from one goroutine,
+// so recording an event never waits on the single sqlite writer. when the
+// queue is full events are dropped and logged rather than blocking a request
+type Writer struct {
+	db            *sql.DB
+	events        chan Event
+	flushInterval time.Duration
+	dropped       atomic.Int64
+}
+
+// NewWriter takes the single-writer handle. bufferSize bounds how many events
+// can wait for the next flush
+func NewWriter(db *sql.DB, bufferSize int, flushInterval time.Duration) *Writer {
+	return &Writer{
+		db:            db,
+		events:        make(chan Event, bufferSize),
+		flushInterval: flushInterval,
+	}
+}
+
+// Record queues ev without blocking
+func (w *Writer) Record(ev Event) {
+	select {
+	case w.events <- ev:
+	default:
+		dropped := w.dropped.Add(1)
+		slog.Warn("audit queue full, dropping event", "action", ev.Action, "entity_type", ev.EntityType, "dropped_total", dropped)
+	}
+}
+
+// Run flushes a batch whenever it fills or flushInterval passes, until ctx is
+// cancelled. it then flushes everything still queued before returning, so
+// callers should cancel ctx only after the http server has drained
+func (w *Writer) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, maxBatchSize)
+	for {
+		select {
+		case ev := <-w.events:
+			batch = append(batch, ev)
+			if len(batch) == maxBatchSize {
+				batch = w.flush(ctx, batch)
+			}
+		case <-ticker.C:
+			batch = w.flush(ctx, batch)
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), finalFlushTimeout)
+			defer cancel()
+			for {
+				select {
+				case ev := <-w.events:
+					batch = append(batch, ev)
+					if len(batch) == maxBatchSize {
+						batch = w.flush(flushCtx, batch)
+					}
+				default:
+					w.flush(flushCtx, batch)
+					slog.Info("audit writer stopped")
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush inserts batch and returns it emptied for reuse. a failed insert is
+// logged and the batch discarded, since retrying would hold up newer events
+func (w *Writer) flush(ctx context.Context, batch []Event) []Event {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := w.insert(ctx, batch); err != nil {
+		slog.Error("writing audit events failed", "error", err, "events", len(batch))
+	}
+	return batch[:0]
+}
+
+func (w *Writer) insert(ctx context.Context, batch []Event) error {
+	var query strings.Builder
+	query.WriteString("INSERT INTO audit_log (id, household_id, user_id, action, entity_type, entity_id, metadata, ip_address) VALUES ")
+	args := make([]any, 0, len(batch)*8)
+	for i, ev := range batch {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(?, ?, ?, ?, ?, ?, ?, ?)")
+		args = append(args, ulid.New(), ev.HouseholdID, nullable(ev.UserID), ev.Action, ev.EntityType,
+			nullable(ev.EntityID), nullable(ev.Metadata), nullable(ev.IPAddress))
+	}
+
+	if _, err := w.db.ExecContext(ctx, query.String(), args...); err != nil {
+		return fmt.Errorf("inserting %d audit events: %w", len(batch), err)
+	}
+	return nil
+}
+
+func nullable(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/testutil"
+)
+
+func countAuditRows(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM audit_log").Scan(&n); err != nil {
+		t.Fatalf("counting audit rows: %v", err)
+	}
+	return n
+}
+
+// startWriter runs w until the returned stop func cancels it and waits
+func startWriter(w *Writer) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestWriterInsertsEveryQueuedEvent(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	household := testutil.CreateTestHousehold(t, db)
+
+	const n = 250
+	w := NewWriter(db, n, 10*time.Millisecond)
+	stop := startWriter(w)
+	defer stop()
+
+	for i := 0; i < n; i++ {
+		w.Record(Event{HouseholdID: household.ID, Action: "test.event", EntityType: "test"})
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for countAuditRows(t, db) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d audit rows, got %d", n, countAuditRows(t, db))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWriterFlushesOnShutdown(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	household := testutil.CreateTestHousehold(t, db)
+
+	// an interval this long means only the shutdown flush can write them
+	w := NewWriter(db, 10, time.Hour)
+	stop := startWriter(w)
+
+	for i := 0; i < 5; i++ {
+		w.Record(Event{HouseholdID: household.ID, Action: "test.event", EntityType: "test", IPAddress: "203.0.113.7"})
+	}
+	stop()
+
+	if got := countAuditRows(t, db); got != 5 {
+		t.Fatalf("expected shutdown to flush 5 events, got %d", got)
+	}
+	var userID, ip sql.NullString
+	if err := db.QueryRow("SELECT user_id, ip_address FROM audit_log LIMIT 1").Scan(&userID, &ip); err != nil {
+		t.Fatal(err)
+	}
+	if userID.Valid || ip.String != "203.0.113.7" {
+		t.Errorf("expected empty user id stored as NULL and ip kept, got %v %v", userID, ip)
+	}
+}
+
+func TestWriterDropsWhenQueueFull(t *testing.T) {
+	db := testutil.NewTestDB(t)
+
+	// not running, so nothing drains the queue
+	w := NewWriter(db, 2, time.Hour)
+	for i := 0; i < 3; i++ {
+		w.Record(Event{HouseholdID: "h1", Action: "test.event", EntityType: "test"})
+	}
+	if got := w.dropped.Load(); got != 1 {
+		t.Errorf("expected 1 dropped event, got %d", got)
+	}
+}
@@ -0,0 +1,119 @@
+package bootstrap
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/shelterkin/shelterkin/internal/crypto"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+)
+
+const (
+	configKeySalt      = "encryption_salt"
+	configKeyTestValue = "encryption_test_value"
+	testValuePlaintext = "shelterkin-encryption-verify"
+)
+
+var (
+	// ErrNotInitialized means the first server start hasn't stored a salt or
+	// verification token yet
+	ErrNotInitialized = errors.New("encryption has not been initialized, start the server once to set it up")
+
+	// ErrSecretChanged means the configured secret can't decrypt the stored
+	// verification token, almost always because ENCRYPTION_SECRET was rotated
+	// or lost. the message is written for the operator reading startup logs
+	ErrSecretChanged = errors.New("ENCRYPTION_SECRET does not match the secret this database was created with, " +
+		"so existing data cannot be decrypted. restore the previous ENCRYPTION_SECRET and restart")
+)
+
+// EnsureSalt returns the stored salt, generating and storing one on first run
+func EnsureSalt(db *sql.DB) ([]byte, error) {
+	salt, err := ReadSalt(db)
+	if !errors.Is(err, ErrNotInitialized) {
+		return salt, err
+	}
+
+	salt, err = crypto.GenerateSalt()
+	if err != nil {
+		return nil, fmt.Errorf("generating salt: %w", err)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(salt)
+	queries := dbgen.New(db)
+	if err := queries.SetConfig(context.Background(), dbgen.SetConfigParams{Key: configKeySalt, Value: encoded}); err != nil {
+		return nil, fmt.Errorf("storing salt: %w", err)
+	}
+
+	slog.Info("generated new encryption salt")
+	return salt, nil
+}
+
+// ReadSalt returns the stored salt without writing anything
+func ReadSalt(db *sql.DB) ([]byte, error) {
+	encoded, err := readConfig(db, configKeySalt)
+	if err != nil {
+		return nil, err
+	}
+	salt, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decoding salt: %w", err)
+	}
+	return salt, nil
+}
+
+// VerifyKey checks enc against the stored verification token, storing one on
+// first run
+func VerifyKey(db *sql.DB, enc *crypto.Encryptor) error {
+	err := CheckKey(db, enc)
+	if !errors.Is(err, ErrNotInitialized) {
+		return err
+	}
+
+	encrypted, err := enc.Encrypt(testValuePlaintext)
+	if err != nil {
+		return fmt.Errorf("encrypting test value: %w", err)
+	}
+	queries := dbgen.New(db)
+	if err := queries.SetConfig(context.Background(), dbgen.SetConfigParams{Key: configKeyTestValue, Value: encrypted}); err != nil {
+		return fmt.Errorf("storing test value: %w", err)
+	}
+
+	slog.Info("stored encryption verification token")
+	return nil
+}
+
+// CheckKey is VerifyKey without the first-run write, for read-only handles
+func CheckKey(db *sql.DB, enc *crypto.Encryptor) error {
+	stored, err := readConfig(db, configKeyTestValue)
+	if err != nil {
+		return err
+	}
+
+	// only an authentication failure points at the secret. a token that
+	// isn't even well-formed was damaged in the database, and restoring an
+	// old secret would not help
+	decrypted, err := enc.Decrypt(stored)
+	if errors.Is(err, crypto.ErrDecryptFailed) || (err == nil && decrypted != testValuePlaintext) {
+		return ErrSecretChanged
+	}
+	if err != nil {
+		return fmt.Errorf("stored encryption verification token is corrupted: %w", err)
+	}
+
+	return nil
+}
+
+func readConfig(db *sql.DB, key string) (string, error) {
+	value, err := dbgen.New(db).GetConfig(context.Background(), key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotInitialized
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", key, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,92 @@
+package bootstrap
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shelterkin/shelterkin/internal/crypto"
+	"github.com/shelterkin/shelterkin/internal/testutil"
+)
+
+func newEncryptor(t *testing.T, secret string, salt []byte) *crypto.Encryptor {
+	t.Helper()
+	enc, err := crypto.NewEncryptor(crypto.DeriveKey(secret, salt))
+	if err != nil {
+		t.Fatalf("creating encryptor: %v", err)
+	}
+	return enc
+}
+
+func TestFirstRunStoresSaltAndToken(t *testing.T) {
+	db := testutil.NewTestDB(t)
+
+	if _, err := ReadSalt(db); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("expected ErrNotInitialized before first run, got %v", err)
+	}
+
+	salt, err := EnsureSalt(db)
+	if err != nil {
+		t.Fatalf("EnsureSalt: %v", err)
+	}
+	again, err := EnsureSalt(db)
+	if err != nil {
+		t.Fatalf("EnsureSalt second call: %v", err)
+	}
+	if !bytes.Equal(salt, again) {
+		t.Error("expected the stored salt to be reused")
+	}
+
+	enc := newEncryptor(t, "first-secret-0123456789", salt)
+	if err := CheckKey(db, enc); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("expected ErrNotInitialized before the token is stored, got %v", err)
+	}
+	if err := VerifyKey(db, enc); err != nil {
+		t.Fatalf("VerifyKey first run: %v", err)
+	}
+	if err := CheckKey(db, enc); err != nil {
+		t.Errorf("expected the stored token to verify, got %v", err)
+	}
+}
+
+func TestVerifyKeyRejectsChangedSecret(t *testing.T) {
+	db := testutil.NewTestDB(t)
+
+	salt, err := EnsureSalt(db)
+	if err != nil {
+		t.Fatalf("EnsureSalt: %v", err)
+	}
+	if err := VerifyKey(db, newEncryptor(t, "first-secret-0123456789", salt)); err != nil {
+		t.Fatalf("VerifyKey first run: %v", err)
+	}
+
+	err = VerifyKey(db, newEncryptor(t, "rotated-secret-0123456789", salt))
+	if !errors.Is(err, ErrSecretChanged) {
+		t.Fatalf("expected ErrSecretChanged, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "restore the previous ENCRYPTION_SECRET") {
+		t.Errorf("expected recovery guidance in the error, got %q", err)
+	}
+}
+
+func TestCheckKeyReportsCorruptedToken(t *testing.T) {
+	db := testutil.NewTestDB(t)
+
+	salt, err := EnsureSalt(db)
+	if err != nil {
+		t.Fatalf("EnsureSalt: %v", err)
+	}
+	enc := newEncryptor(t, "first-secret-0123456789", salt)
+	if err := VerifyKey(db, enc); err != nil {
+		t.Fatalf("VerifyKey first run: %v", err)
+	}
+	if _, err := db.Exec("UPDATE config SET value = 'dHJ1bmNhdGVk' WHERE key = ?", configKeyTestValue); err != nil {
+		t.Fatalf("corrupting token: %v", err)
+	}
+
+	err = CheckKey(db, enc)
+	if errors.Is(err, ErrSecretChanged) || !errors.Is(err, crypto.ErrCiphertextTooShort) {
+		t.Errorf("expected a corrupted token error rather than a changed secret, got %v", err)
+	}
+}
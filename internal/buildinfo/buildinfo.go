@@ -0,0 +1,45 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Version and BuildTime are stamped at link time, see the Makefile:
+//
+//	-ldflags "-X github.com/shelterkin/shelterkin/internal/buildinfo.Version=v1.2.0"
+var (
+	Version   = "dev"
+	BuildTime = ""
+)
+
+// Info is what /version reports, deliberately nothing beyond what a release
+// page would already show
+type Info struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"go_version"`
+	BuildTime string `json:"build_time,omitempty"`
+}
+
+// Get fills gaps the linker didn't stamp from the embedded build info: the
+// module version for `go install pkg@version` builds, and the vcs commit time
+// in place of a build time
+func Get() Info {
+	info := Info{Version: Version, GoVersion: runtime.Version(), BuildTime: BuildTime}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	if info.Version == "dev" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+	if info.BuildTime == "" {
+		for _, setting := range bi.Settings {
+			if setting.Key == "vcs.time" {
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,19 @@
+package clock
+
+import "time"
+
+// Clock is the time source for anything that compares against expiry windows,
+// so tests can move time forward instead of rewriting rows
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func Real() Clock {
+	return realClock{}
+}
@@ -2,66 +2,314 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	Port             int
+	Port int
+	// ListenAddr is the host to bind, empty for every interface, or an
+	// absolute path to listen on a unix socket instead of a tcp port
+	ListenAddr       string
 	DatabasePath     string
 	SessionSecret    string
 	EncryptionSecret string
 	CSRFKey          string
 	DataDir          string
+	DataDirMode      os.FileMode
 	LogLevel         string
-	BaseURL          string
+	LogQueryStrings  bool
+	// CSPReportOnly sends the content security policy as report-only, so
+	// violations are logged instead of blocked
+	CSPReportOnly bool
+	// BaseURL is the absolute http or https url the app is served from,
+	// without a trailing slash. BaseHost is its host and any port, for
+	// comparing against request origins
+	BaseURL  string
+	BaseHost string
+	// AllowedHosts are further hosts requests may address besides BaseHost,
+	// such as a second name the app is reachable under
+	AllowedHosts []string
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For is
+	// believed when working out a client's address
+	TrustedProxies []netip.Prefix
+
+	// EncryptionSecretPrevious are secrets ENCRYPTION_SECRET replaced. data
+	// written under them stays readable, and new writes use the current one
+	EncryptionSecretPrevious []string
+	// HMACSecret keys the email and token lookup hashes. it defaults to
+	// ENCRYPTION_SECRET, and must be pinned to the original secret before
+	// that is rotated, or every stored hash is orphaned
+	HMACSecret string
+
+	// HMACPepper is mixed into email and token lookup hashes. it is optional,
+	// but setting or changing it orphans every stored hash: existing users
+	// cannot be found by email and outstanding invite links stop working
+	HMACPepper string
+
+	// WebhookURL receives signed security events when set. WebhookSecret is
+	// the shared key the receiver checks signatures with
+	WebhookURL    string
+	WebhookSecret string
+
+	TLSCertFile        string
+	TLSKeyFile         string
+	TLSAutocertDomains []string
+
+	RequestTimeout        time.Duration
+	ReadHeaderTimeout     time.Duration
+	MaintenanceInterval   time.Duration
+	CheckpointInterval    time.Duration
+	LoginAttemptRetention time.Duration
+	// CoarsenLoginIPs stores only the /24 or /48 a login attempt came from,
+	// so rate limiting works per network instead of per address
+	CoarsenLoginIPs bool
+
+	SQLiteBusyTimeoutMS int
+	SQLiteCacheSize     int
+	SQLiteMmapSize      int
+	SQLiteSynchronous   string
+}
+
+// Listener returns the network and address to bind: the socket path when
+// ListenAddr is one, otherwise ListenAddr joined with Port
+func (c *Config) Listener() (network, address string) {
+	if strings.HasPrefix(c.ListenAddr, "/") {
+		return "unix", c.ListenAddr
+	}
+	return "tcp", net.JoinHostPort(c.ListenAddr, strconv.Itoa(c.Port))
 }
 
+// validListenHost accepts an ip literal or a dns name, but not host:port
+func validListenHost(host string) bool {
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Load reads settings from the environment, filling unset variables from a
+// .env file in the working directory when one exists. when CONFIG_FILE points
+// at a yaml file its values fill in anything both leave unset
 func Load() (*Config, error) {
+	src, err := newSource(defaultDotenvPath)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
-		Port:         envInt("PORT", 8080),
-		DatabasePath: envString("DATABASE_PATH", "data/shelterkin.db"),
-		DataDir:      envString("DATA_DIR", "data"),
-		LogLevel:     envString("LOG_LEVEL", "info"),
-		BaseURL:      envString("BASE_URL", "http://localhost:8080"),
+		Port:         src.int("PORT", 8080),
+		ListenAddr:   src.get("LISTEN_ADDR"),
+		DatabasePath: src.string("DATABASE_PATH", "data/shelterkin.db"),
+		DataDir:      src.string("DATA_DIR", "data"),
+		LogLevel:     src.string("LOG_LEVEL", "info"),
+		BaseURL:      src.string("BASE_URL", "http://localhost:8080"),
+		AllowedHosts: src.list("ALLOWED_HOSTS"),
+
+		// off by default since query strings can carry tokens
+		LogQueryStrings: src.bool("LOG_QUERY_STRINGS"),
+
+		CSPReportOnly: src.bool("CSP_REPORT_ONLY"),
+
+		TLSCertFile:        src.get("TLS_CERT_FILE"),
+		TLSKeyFile:         src.get("TLS_KEY_FILE"),
+		TLSAutocertDomains: src.list("TLS_AUTOCERT_DOMAINS"),
+
+		RequestTimeout:        src.duration("REQUEST_TIMEOUT", 20*time.Second),
+		ReadHeaderTimeout:     src.duration("READ_HEADER_TIMEOUT", 5*time.Second),
+		MaintenanceInterval:   src.duration("MAINTENANCE_INTERVAL", time.Hour),
+		CheckpointInterval:    src.duration("WAL_CHECKPOINT_INTERVAL", 15*time.Minute),
+		LoginAttemptRetention: src.duration("LOGIN_ATTEMPT_RETENTION", 30*24*time.Hour),
+		CoarsenLoginIPs:       src.bool("COARSEN_LOGIN_IPS"),
+
+		SQLiteBusyTimeoutMS: src.int("SQLITE_BUSY_TIMEOUT_MS", 5000),
+		SQLiteCacheSize:     src.int("SQLITE_CACHE_SIZE", 0),
+		SQLiteMmapSize:      src.int("SQLITE_MMAP_SIZE", 0),
+		SQLiteSynchronous:   strings.ToUpper(src.get("SQLITE_SYNCHRONOUS")),
+
+		SessionSecret:    src.get("SESSION_SECRET"),
+		EncryptionSecret: src.get("ENCRYPTION_SECRET"),
+		HMACSecret:       src.get("HMAC_SECRET"),
+		CSRFKey:          src.get("CSRF_KEY"),
+		HMACPepper:       src.get("HMAC_PEPPER"),
+		WebhookURL:       src.get("WEBHOOK_URL"),
+		WebhookSecret:    src.get("WEBHOOK_SECRET"),
+	}
+
+	cfg.EncryptionSecretPrevious = src.list("ENCRYPTION_SECRET_PREVIOUS")
+	dataDirMode := src.get("DATA_DIR_MODE")
+	trustedProxies := src.list("TRUSTED_PROXIES")
+
+	if err := src.checkUnused(); err != nil {
+		return nil, err
 	}
 
-	var missing []string
+	missing := src.invalid
 
-	cfg.SessionSecret = os.Getenv("SESSION_SECRET")
 	if len(cfg.SessionSecret) < 32 {
 		missing = append(missing, "SESSION_SECRET (must be at least 32 characters)")
 	}
 
-	cfg.EncryptionSecret = os.Getenv("ENCRYPTION_SECRET")
 	if len(cfg.EncryptionSecret) < 16 {
 		missing = append(missing, "ENCRYPTION_SECRET (must be at least 16 characters)")
 	}
 
-	cfg.CSRFKey = os.Getenv("CSRF_KEY")
+	for _, previous := range cfg.EncryptionSecretPrevious {
+		if len(previous) < 16 {
+			missing = append(missing, "ENCRYPTION_SECRET_PREVIOUS (each secret must be at least 16 characters)")
+			break
+		}
+	}
+	// lookup hashes are keyed from the secret, so rotating it without
+	// pinning HMAC_SECRET would make every account unfindable by email
+	if len(cfg.EncryptionSecretPrevious) > 0 && cfg.HMACSecret == "" {
+		missing = append(missing, "HMAC_SECRET (must be set to the original ENCRYPTION_SECRET while rotating)")
+	}
+
 	if len(cfg.CSRFKey) != 32 {
 		missing = append(missing, "CSRF_KEY (must be exactly 32 characters)")
 	}
 
+	for _, secret := range cfg.secrets() {
+		if isPlaceholderSecret(secret.value) {
+			missing = append(missing, secret.name+" (looks like a placeholder, generate a random value)")
+		}
+	}
+
+	if cfg.HMACPepper != "" && len(cfg.HMACPepper) < 16 {
+		missing = append(missing, "HMAC_PEPPER (must be at least 16 characters when set)")
+	}
+
+	if cfg.WebhookURL != "" {
+		if u, err := url.Parse(cfg.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			missing = append(missing, "WEBHOOK_URL (must be an absolute http or https url)")
+		}
+		if len(cfg.WebhookSecret) < 32 {
+			missing = append(missing, "WEBHOOK_SECRET (must be at least 32 characters when WEBHOOK_URL is set)")
+		}
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		missing = append(missing, "TLS_CERT_FILE and TLS_KEY_FILE (must be set together)")
+	}
+	if cfg.TLSCertFile != "" && len(cfg.TLSAutocertDomains) > 0 {
+		missing = append(missing, "TLS_AUTOCERT_DOMAINS (cannot be combined with TLS_CERT_FILE)")
+	}
+	// autocert answers TLS-ALPN-01 challenges, which the CA only sends to 443
+	if len(cfg.TLSAutocertDomains) > 0 && cfg.Port != 443 {
+		missing = append(missing, "PORT (must be 443 when TLS_AUTOCERT_DOMAINS is set)")
+	}
+
+	// an invalid mode is refused rather than defaulted, since the fallback
+	// could be wider than what the operator asked for
+	cfg.DataDirMode = 0700
+	if dataDirMode != "" {
+		n, err := strconv.ParseUint(dataDirMode, 8, 32)
+		if err != nil || n > 0777 || n&0700 != 0700 {
+			missing = append(missing, "DATA_DIR_MODE (must be an octal mode like 0700 that gives the owner rwx)")
+		} else {
+			cfg.DataDirMode = os.FileMode(n)
+		}
+	}
+
+	// links are built by appending a path, so a query, fragment or trailing
+	// slash would corrupt every one of them
+	if u, err := url.Parse(cfg.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") ||
+		u.Host == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		missing = append(missing, "BASE_URL (must be an absolute http or https url like https://shelterkin.example.com)")
+	} else {
+		cfg.BaseURL = strings.TrimRight(u.String(), "/")
+		cfg.BaseHost = strings.ToLower(u.Host)
+	}
+
+	for i, host := range cfg.AllowedHosts {
+		if u, err := url.Parse("http://" + host); err != nil || u.Host != host || u.User != nil {
+			missing = append(missing, "ALLOWED_HOSTS (must be a comma-separated list of host or host:port values)")
+			break
+		}
+		cfg.AllowedHosts[i] = strings.ToLower(host)
+	}
+
+	// a bare address trusts just that host
+	for _, proxy := range trustedProxies {
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				missing = append(missing, "TRUSTED_PROXIES (must be a comma-separated list of IP addresses or CIDR ranges)")
+				break
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		cfg.TrustedProxies = append(cfg.TrustedProxies, prefix.Masked())
+	}
+
+	if cfg.ListenAddr != "" && !strings.HasPrefix(cfg.ListenAddr, "/") && !validListenHost(cfg.ListenAddr) {
+		missing = append(missing, "LISTEN_ADDR (must be a host name or IP address without a port, or an absolute socket path)")
+	}
+	if strings.HasPrefix(cfg.ListenAddr, "/") && len(cfg.TLSAutocertDomains) > 0 {
+		missing = append(missing, "LISTEN_ADDR (a socket path cannot be combined with TLS_AUTOCERT_DOMAINS)")
+	}
+
+	// the janitor deletes attempts older than this, so a shorter retention
+	// would let failures age out before the rate limiter has counted them
+	if cfg.LoginAttemptRetention < time.Hour {
+		missing = append(missing, "LOGIN_ATTEMPT_RETENTION (must be at least 1h)")
+	}
+
+	if cfg.SQLiteBusyTimeoutMS < 1 || cfg.SQLiteBusyTimeoutMS > 10*60*1000 {
+		missing = append(missing, "SQLITE_BUSY_TIMEOUT_MS (must be between 1 and 600000)")
+	}
+	// negative cache sizes are KiB, positive ones are pages
+	if cfg.SQLiteCacheSize < -1<<20 || cfg.SQLiteCacheSize > 1<<20 {
+		missing = append(missing, "SQLITE_CACHE_SIZE (must be between -1048576 and 1048576)")
+	}
+	if cfg.SQLiteMmapSize < 0 || cfg.SQLiteMmapSize > 1<<34 {
+		missing = append(missing, "SQLITE_MMAP_SIZE (must be between 0 and 16GiB)")
+	}
+	switch cfg.SQLiteSynchronous {
+	case "", "OFF", "NORMAL", "FULL", "EXTRA":
+	default:
+		missing = append(missing, "SQLITE_SYNCHRONOUS (must be OFF, NORMAL, FULL or EXTRA)")
+	}
+
 	if len(missing) > 0 {
-		return nil, fmt.Errorf("missing or invalid environment variables: %v", missing)
+		return nil, fmt.Errorf("missing or invalid settings: %v", missing)
 	}
 
 	return cfg, nil
 }
 
-func envString(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+// LookupSecret is the secret the lookup hash key is derived from
+func (c *Config) LookupSecret() string {
+	if c.HMACSecret != "" {
+		return c.HMACSecret
 	}
-	return fallback
+	return c.EncryptionSecret
 }
 
-func envInt(key string, fallback int) int {
-	if v := os.Getenv(key); v != "" {
-		if n, err := strconv.Atoi(v); err == nil {
-			return n
-		}
-	}
-	return fallback
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" || len(c.TLSAutocertDomains) > 0
+}
+
+// SecureCookies reports whether browsers reach the app over https, either
+// served directly or through a proxy named in BASE_URL. cookies marked
+// Secure are dropped on a plain http deployment
+func (c *Config) SecureCookies() bool {
+	return c.TLSEnabled() || strings.HasPrefix(c.BaseURL, "https://")
 }
@@ -1,8 +1,13 @@
 package config
 
 import (
+	"net/netip"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func setTestEnv(t *testing.T) {
@@ -29,6 +34,12 @@ func TestLoadDefaults(t *testing.T) {
 	if cfg.LogLevel != "info" {
 		t.Errorf("expected log level 'info', got %q", cfg.LogLevel)
 	}
+	if cfg.LogQueryStrings {
+		t.Error("expected query string logging to be off by default")
+	}
+	if cfg.CSPReportOnly {
+		t.Error("expected the content security policy to be enforced by default")
+	}
 }
 
 func TestLoadCustomPort(t *testing.T) {
@@ -44,6 +55,392 @@ func TestLoadCustomPort(t *testing.T) {
 	}
 }
 
+func TestLoadListenAddr(t *testing.T) {
+	tests := []struct {
+		listenAddr string
+		network    string
+		address    string
+	}{
+		{"", "tcp", ":8080"},
+		{"127.0.0.1", "tcp", "127.0.0.1:8080"},
+		{"::1", "tcp", "[::1]:8080"},
+		{"localhost", "tcp", "localhost:8080"},
+		{"/run/shelterkin/http.sock", "unix", "/run/shelterkin/http.sock"},
+	}
+	for _, tt := range tests {
+		setTestEnv(t)
+		t.Setenv("LISTEN_ADDR", tt.listenAddr)
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("LISTEN_ADDR=%q: unexpected error: %v", tt.listenAddr, err)
+		}
+		network, address := cfg.Listener()
+		if network != tt.network || address != tt.address {
+			t.Errorf("LISTEN_ADDR=%q: expected %s %s, got %s %s", tt.listenAddr, tt.network, tt.address, network, address)
+		}
+	}
+}
+
+func TestLoadInvalidListenAddr(t *testing.T) {
+	for _, listenAddr := range []string{"127.0.0.1:9000", "relative/path.sock", "bad_host", "-leading.example.com"} {
+		setTestEnv(t)
+		t.Setenv("LISTEN_ADDR", listenAddr)
+
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "LISTEN_ADDR") {
+			t.Errorf("LISTEN_ADDR=%q: expected a validation error, got %v", listenAddr, err)
+		}
+	}
+}
+
+func TestLoadBaseURL(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+		host    string
+	}{
+		{"", "http://localhost:8080", "localhost:8080"},
+		{"https://shelterkin.example.com", "https://shelterkin.example.com", "shelterkin.example.com"},
+		{"https://shelterkin.example.com/", "https://shelterkin.example.com", "shelterkin.example.com"},
+		{"HTTPS://Home.Example.com/shelterkin/", "https://Home.Example.com/shelterkin", "home.example.com"},
+		{"http://192.168.1.10:8080", "http://192.168.1.10:8080", "192.168.1.10:8080"},
+	}
+	for _, tt := range tests {
+		setTestEnv(t)
+		t.Setenv("BASE_URL", tt.baseURL)
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("BASE_URL=%q: unexpected error: %v", tt.baseURL, err)
+		}
+		if cfg.BaseURL != tt.want || cfg.BaseHost != tt.host {
+			t.Errorf("BASE_URL=%q: expected %q on %q, got %q on %q", tt.baseURL, tt.want, tt.host, cfg.BaseURL, cfg.BaseHost)
+		}
+	}
+}
+
+func TestLoadInvalidBaseURL(t *testing.T) {
+	for _, baseURL := range []string{"httpsfoo", "ftp://example.com", "//example.com", "https://", "example.com/app", "https://example.com/?next=1", "https://user:pw@example.com"} {
+		setTestEnv(t)
+		t.Setenv("BASE_URL", baseURL)
+
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "BASE_URL") {
+			t.Errorf("BASE_URL=%q: expected a validation error, got %v", baseURL, err)
+		}
+	}
+}
+
+func TestLoadAllowedHosts(t *testing.T) {
+	setTestEnv(t)
+	t.Setenv("ALLOWED_HOSTS", "Shelterkin.local, 192.168.1.10:8080")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"shelterkin.local", "192.168.1.10:8080"}; !slices.Equal(cfg.AllowedHosts, want) {
+		t.Errorf("expected %v, got %v", want, cfg.AllowedHosts)
+	}
+
+	for _, hosts := range []string{"https://shelterkin.local", "shelterkin.local/app", "user@shelterkin.local"} {
+		t.Setenv("ALLOWED_HOSTS", hosts)
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "ALLOWED_HOSTS") {
+			t.Errorf("ALLOWED_HOSTS=%q: expected a validation error, got %v", hosts, err)
+		}
+	}
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+	setTestEnv(t)
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.10, fd00::1/64")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.1.10/32"),
+		netip.MustParsePrefix("fd00::/64"),
+	}
+	if !slices.Equal(cfg.TrustedProxies, want) {
+		t.Errorf("expected %v, got %v", want, cfg.TrustedProxies)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "proxy.internal")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "TRUSTED_PROXIES") {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
+
+func TestLoadWebhook(t *testing.T) {
+	setTestEnv(t)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.WebhookURL != "" {
+		t.Errorf("expected webhooks off by default, got %q", cfg.WebhookURL)
+	}
+
+	t.Setenv("WEBHOOK_URL", "https://hooks.example.com/shelterkin")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "WEBHOOK_SECRET") {
+		t.Errorf("expected a url without a secret to be refused, got %v", err)
+	}
+
+	t.Setenv("WEBHOOK_SECRET", "wh-7c1f0d9a4b2e8f63a5d0c4b9e1f7a2d6")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.WebhookURL != "https://hooks.example.com/shelterkin" || cfg.WebhookSecret == "" {
+		t.Errorf("expected the webhook settings, got %q", cfg.WebhookURL)
+	}
+
+	t.Setenv("WEBHOOK_URL", "hooks.example.com")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "WEBHOOK_URL") {
+		t.Errorf("expected a relative url to be refused, got %v", err)
+	}
+}
+
+func TestLoadEncryptionSecretRotation(t *testing.T) {
+	setTestEnv(t)
+	original := os.Getenv("ENCRYPTION_SECRET")
+	t.Setenv("ENCRYPTION_SECRET", "rotated-secret-5f2c9e71b0d4")
+	t.Setenv("ENCRYPTION_SECRET_PREVIOUS", original)
+
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "HMAC_SECRET") {
+		t.Errorf("expected rotation without a pinned HMAC_SECRET to be refused, got %v", err)
+	}
+
+	t.Setenv("HMAC_SECRET", original)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(cfg.EncryptionSecretPrevious, []string{original}) || cfg.LookupSecret() != original {
+		t.Errorf("expected the original secret to stay for decryption and lookups, got %v and %q", cfg.EncryptionSecretPrevious, cfg.LookupSecret())
+	}
+}
+
+func TestLookupSecretDefaultsToEncryptionSecret(t *testing.T) {
+	setTestEnv(t)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.LookupSecret() != cfg.EncryptionSecret {
+		t.Errorf("expected lookups keyed from ENCRYPTION_SECRET by default")
+	}
+}
+
+func TestLoadInvalidDurations(t *testing.T) {
+	for _, key := range []string{"REQUEST_TIMEOUT", "READ_HEADER_TIMEOUT", "MAINTENANCE_INTERVAL", "WAL_CHECKPOINT_INTERVAL"} {
+		for _, value := range []string{"abc", "0s", "-5m"} {
+			setTestEnv(t)
+			t.Setenv(key, value)
+
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), key) {
+				t.Errorf("%s=%q: expected a validation error, got %v", key, value, err)
+			}
+		}
+		t.Setenv(key, "")
+	}
+}
+
+func TestLoadDataDirMode(t *testing.T) {
+	setTestEnv(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.DataDirMode != 0700 {
+		t.Errorf("expected default mode 0700, got %#o", cfg.DataDirMode)
+	}
+
+	t.Setenv("DATA_DIR_MODE", "0750")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.DataDirMode != 0750 {
+		t.Errorf("expected mode 0750, got %#o", cfg.DataDirMode)
+	}
+
+	for _, mode := range []string{"750x", "0600", "1777", "rwx"} {
+		t.Setenv("DATA_DIR_MODE", mode)
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "DATA_DIR_MODE") {
+			t.Errorf("DATA_DIR_MODE=%q: expected a validation error, got %v", mode, err)
+		}
+	}
+}
+
+func TestLoadMaintenanceInterval(t *testing.T) {
+	setTestEnv(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.MaintenanceInterval != time.Hour {
+		t.Errorf("expected default interval 1h, got %v", cfg.MaintenanceInterval)
+	}
+	if cfg.ReadHeaderTimeout != 5*time.Second {
+		t.Errorf("expected default read header timeout 5s, got %v", cfg.ReadHeaderTimeout)
+	}
+	if cfg.RequestTimeout != 20*time.Second {
+		t.Errorf("expected default request timeout 20s, got %v", cfg.RequestTimeout)
+	}
+	if cfg.CheckpointInterval != 15*time.Minute {
+		t.Errorf("expected default checkpoint interval 15m, got %v", cfg.CheckpointInterval)
+	}
+	if cfg.LoginAttemptRetention != 30*24*time.Hour {
+		t.Errorf("expected default retention 720h, got %v", cfg.LoginAttemptRetention)
+	}
+
+	t.Setenv("MAINTENANCE_INTERVAL", "15m")
+	t.Setenv("LOGIN_ATTEMPT_RETENTION", "168h")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.MaintenanceInterval != 15*time.Minute {
+		t.Errorf("expected interval 15m, got %v", cfg.MaintenanceInterval)
+	}
+	if cfg.LoginAttemptRetention != 168*time.Hour {
+		t.Errorf("expected retention 168h, got %v", cfg.LoginAttemptRetention)
+	}
+}
+
+func TestLoadLoginAttemptPrivacy(t *testing.T) {
+	setTestEnv(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.CoarsenLoginIPs {
+		t.Error("expected exact login ips to be stored by default")
+	}
+
+	t.Setenv("COARSEN_LOGIN_IPS", "true")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.CoarsenLoginIPs {
+		t.Error("expected COARSEN_LOGIN_IPS to enable coarsening")
+	}
+
+	t.Setenv("LOGIN_ATTEMPT_RETENTION", "10m")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "LOGIN_ATTEMPT_RETENTION") {
+		t.Errorf("expected a retention shorter than 1h to be refused, got %v", err)
+	}
+}
+
+func TestLoadTLS(t *testing.T) {
+	setTestEnv(t)
+	t.Setenv("TLS_CERT_FILE", "/etc/shelterkin/cert.pem")
+	t.Setenv("TLS_KEY_FILE", "/etc/shelterkin/key.pem")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.TLSEnabled() {
+		t.Error("expected TLS to be enabled")
+	}
+}
+
+func TestSecureCookies(t *testing.T) {
+	tests := []struct {
+		cfg  Config
+		want bool
+	}{
+		{Config{BaseURL: "http://192.168.1.10:8080"}, false},
+		{Config{BaseURL: "https://shelterkin.example.com"}, true},
+		{Config{BaseURL: "http://localhost:8443", TLSCertFile: "/etc/shelterkin/cert.pem"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.SecureCookies(); got != tt.want {
+			t.Errorf("%+v: expected %v, got %v", tt.cfg, tt.want, got)
+		}
+	}
+}
+
+func TestLoadTLSRequiresCertAndKey(t *testing.T) {
+	setTestEnv(t)
+	t.Setenv("TLS_CERT_FILE", "/etc/shelterkin/cert.pem")
+
+	if _, err := Load(); err == nil {
+		t.Fatal("expected error when TLS_KEY_FILE is missing")
+	}
+}
+
+func TestLoadAutocertDomains(t *testing.T) {
+	setTestEnv(t)
+	t.Setenv("TLS_AUTOCERT_DOMAINS", "care.example.com, www.care.example.com")
+	t.Setenv("PORT", "443")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.TLSAutocertDomains) != 2 || cfg.TLSAutocertDomains[1] != "www.care.example.com" {
+		t.Errorf("unexpected autocert domains: %v", cfg.TLSAutocertDomains)
+	}
+	if !cfg.TLSEnabled() {
+		t.Error("expected TLS to be enabled")
+	}
+}
+
+func TestLoadAutocertRequiresPort443(t *testing.T) {
+	setTestEnv(t)
+	t.Setenv("TLS_AUTOCERT_DOMAINS", "care.example.com")
+
+	if _, err := Load(); err == nil {
+		t.Fatal("expected error when autocert is used on a port other than 443")
+	}
+}
+
+func TestLoadSQLitePragmas(t *testing.T) {
+	setTestEnv(t)
+	t.Setenv("SQLITE_BUSY_TIMEOUT_MS", "15000")
+	t.Setenv("SQLITE_CACHE_SIZE", "-8192")
+	t.Setenv("SQLITE_SYNCHRONOUS", "normal")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.SQLiteBusyTimeoutMS != 15000 {
+		t.Errorf("expected busy timeout 15000, got %d", cfg.SQLiteBusyTimeoutMS)
+	}
+	if cfg.SQLiteCacheSize != -8192 {
+		t.Errorf("expected cache size -8192, got %d", cfg.SQLiteCacheSize)
+	}
+	if cfg.SQLiteSynchronous != "NORMAL" {
+		t.Errorf("expected synchronous NORMAL, got %q", cfg.SQLiteSynchronous)
+	}
+}
+
+func TestLoadSQLitePragmasOutOfRange(t *testing.T) {
+	setTestEnv(t)
+	t.Setenv("SQLITE_BUSY_TIMEOUT_MS", "0")
+	t.Setenv("SQLITE_SYNCHRONOUS", "sometimes")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("expected error for out of range pragmas")
+	}
+	for _, key := range []string{"SQLITE_BUSY_TIMEOUT_MS", "SQLITE_SYNCHRONOUS"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("expected error to mention %s, got %v", key, err)
+		}
+	}
+}
+
 func TestLoadMissingSessionSecret(t *testing.T) {
 	t.Setenv("SESSION_SECRET", "")
 	t.Setenv("ENCRYPTION_SECRET", "test-encryption-secret")
@@ -85,3 +482,253 @@ func TestLoadAllMissing(t *testing.T) {
 		t.Fatal("expected error when all secrets missing")
 	}
 }
+
+func writeConfigFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "shelterkin.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatalf("writing config file: %v", err)
+	}
+	return path
+}
+
+const fileSecrets = `
+session_secret: file-session-secret-that-is-long-enough
+encryption_secret: file-encryption-secret
+csrf_key: "file-csrf-key-of-32-characters!!"
+`
+
+func TestLoadFromFile(t *testing.T) {
+	os.Clearenv()
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, fileSecrets+`
+port: 9191
+database_path: /srv/shelterkin/app.db
+maintenance_interval: 2h
+`))
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Port != 9191 {
+		t.Errorf("expected port 9191 from file, got %d", cfg.Port)
+	}
+	if cfg.DatabasePath != "/srv/shelterkin/app.db" {
+		t.Errorf("expected database path from file, got %q", cfg.DatabasePath)
+	}
+	if cfg.MaintenanceInterval != 2*time.Hour {
+		t.Errorf("expected interval 2h from file, got %v", cfg.MaintenanceInterval)
+	}
+	if cfg.CSRFKey != "file-csrf-key-of-32-characters!!" {
+		t.Errorf("expected csrf key from file, got %q", cfg.CSRFKey)
+	}
+	if cfg.LogLevel != "info" {
+		t.Errorf("expected default log level for unset key, got %q", cfg.LogLevel)
+	}
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	os.Clearenv()
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, fileSecrets+"port: 9191\n"))
+	t.Setenv("PORT", "7070")
+	t.Setenv("ENCRYPTION_SECRET", "env-encryption-secret")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Port != 7070 {
+		t.Errorf("expected env port 7070 to win, got %d", cfg.Port)
+	}
+	if cfg.EncryptionSecret != "env-encryption-secret" {
+		t.Errorf("expected env encryption secret to win, got %q", cfg.EncryptionSecret)
+	}
+	if cfg.SessionSecret != "file-session-secret-that-is-long-enough" {
+		t.Errorf("expected session secret from file, got %q", cfg.SessionSecret)
+	}
+}
+
+func TestLoadFileStillValidated(t *testing.T) {
+	os.Clearenv()
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, strings.Replace(fileSecrets, "file-csrf-key-of-32-characters!!", "too-short", 1)))
+
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "CSRF_KEY") {
+		t.Fatalf("expected CSRF_KEY validation error, got %v", err)
+	}
+}
+
+func TestLoadMalformedFile(t *testing.T) {
+	tests := map[string]string{
+		"not a mapping": "- just\n- a list\n",
+		"bad syntax":    "port: [9191\n",
+		"unknown key":   fileSecrets + "prot: 9191\n",
+	}
+	for name, contents := range tests {
+		t.Run(name, func(t *testing.T) {
+			os.Clearenv()
+			t.Setenv("CONFIG_FILE", writeConfigFile(t, contents))
+
+			_, err := Load()
+			if err == nil || !strings.Contains(err.Error(), "config file") {
+				t.Fatalf("expected a config file error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	setTestEnv(t)
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+
+	if _, err := Load(); err == nil {
+		t.Fatal("expected error for a missing config file")
+	}
+}
+
+func writeDotenv(t *testing.T, contents string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(contents), 0600); err != nil {
+		t.Fatalf("writing .env: %v", err)
+	}
+	t.Chdir(dir)
+}
+
+func TestLoadDotenvFillsUnsetVariables(t *testing.T) {
+	os.Clearenv()
+	writeDotenv(t, `# local development secrets
+export SESSION_SECRET="dotenv-session-secret-that-is-long-enough"
+ENCRYPTION_SECRET='dotenv-encryption-secret'
+CSRF_KEY=dotenv-csrf-key-32-characters!!! # inline comment
+
+LOG_LEVEL="debug"
+`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.SessionSecret != "dotenv-session-secret-that-is-long-enough" {
+		t.Errorf("expected double quoted session secret, got %q", cfg.SessionSecret)
+	}
+	if cfg.EncryptionSecret != "dotenv-encryption-secret" {
+		t.Errorf("expected single quoted encryption secret, got %q", cfg.EncryptionSecret)
+	}
+	if cfg.CSRFKey != "dotenv-csrf-key-32-characters!!!" {
+		t.Errorf("expected unquoted csrf key without comment, got %q", cfg.CSRFKey)
+	}
+	if cfg.LogLevel != "debug" {
+		t.Errorf("expected log level from .env, got %q", cfg.LogLevel)
+	}
+	if os.Getenv("LOG_LEVEL") != "" {
+		t.Error(".env values must not be exported into the process environment")
+	}
+}
+
+func TestLoadEnvironmentWinsOverDotenv(t *testing.T) {
+	setTestEnv(t)
+	t.Setenv("LOG_LEVEL", "warn")
+	writeDotenv(t, "LOG_LEVEL=debug\nPORT=9393\n")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.LogLevel != "warn" {
+		t.Errorf("expected environment log level to win, got %q", cfg.LogLevel)
+	}
+	if cfg.Port != 9393 {
+		t.Errorf("expected port from .env, got %d", cfg.Port)
+	}
+}
+
+func TestLoadMalformedDotenv(t *testing.T) {
+	setTestEnv(t)
+	writeDotenv(t, "PORT=9393\nnot a setting\n")
+
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("expected error naming the bad line, got %v", err)
+	}
+}
+
+func TestLoadRefusesPlaceholderSecrets(t *testing.T) {
+	setTestEnv(t)
+	t.Setenv("SESSION_SECRET", "change-me-to-a-random-string-please")
+
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "SESSION_SECRET (looks like a placeholder") {
+		t.Fatalf("expected placeholder refusal, got %v", err)
+	}
+}
+
+func TestSecretWarnings(t *testing.T) {
+	setTestEnv(t)
+	t.Setenv("SESSION_SECRET", strings.Repeat("a", 32))
+	t.Setenv("ENCRYPTION_SECRET", "correcthorsebatterystaple")
+	t.Setenv("CSRF_KEY", "q8Zr2vKx0PbN7sLw4TgYc1HmJe6DuF9a")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("length-valid secrets should only warn, got %v", err)
+	}
+
+	warnings := strings.Join(cfg.SecretWarnings(), "\n")
+	if !strings.Contains(warnings, "SESSION_SECRET uses fewer than") {
+		t.Errorf("expected repeated-character warning, got %q", warnings)
+	}
+	if !strings.Contains(warnings, "ENCRYPTION_SECRET contains only letters") {
+		t.Errorf("expected dictionary word warning, got %q", warnings)
+	}
+	if strings.Contains(warnings, "CSRF_KEY") {
+		t.Errorf("expected random csrf key to pass, got %q", warnings)
+	}
+}
+
+func TestLoadHMACPepper(t *testing.T) {
+	setTestEnv(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.HMACPepper != "" {
+		t.Errorf("expected no pepper by default, got %q", cfg.HMACPepper)
+	}
+
+	t.Setenv("HMAC_PEPPER", "short")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "HMAC_PEPPER") {
+		t.Fatalf("expected short pepper to be rejected, got %v", err)
+	}
+}
+
+func TestDumpRedactsSecrets(t *testing.T) {
+	setTestEnv(t)
+	t.Setenv("PORT", "9191")
+	t.Setenv("DATA_DIR", "/srv/shelterkin")
+	t.Setenv("HMAC_PEPPER", "pepper-value-0123456789")
+	t.Setenv("WEBHOOK_URL", "https://hooks.example.com/shelterkin")
+	t.Setenv("WEBHOOK_SECRET", "wh-7c1f0d9a4b2e8f63a5d0c4b9e1f7a2d6")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out strings.Builder
+	if err := cfg.Dump(&out); err != nil {
+		t.Fatalf("Dump: %v", err)
+	}
+	dump := out.String()
+
+	for _, want := range []string{"9191", "/srv/shelterkin", "http://localhost:8080", "720h0m0s", "0700", "*** (22 characters)", "https://hooks.example.com/shelterkin"} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("expected %q in the dump, got:\n%s", want, dump)
+		}
+	}
+	for _, secret := range []string{cfg.SessionSecret, cfg.EncryptionSecret, cfg.CSRFKey, cfg.HMACPepper, cfg.WebhookSecret} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("expected secret %q to be redacted, got:\n%s", secret, dump)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Dump writes the effective settings under their variable names, for
+// pasting into bug reports. secrets show only whether they are set and how
+// long they are
+func (c *Config) Dump(w io.Writer) error {
+	settings := [][2]string{
+		{"PORT", strconv.Itoa(c.Port)},
+		{"LISTEN_ADDR", c.ListenAddr},
+		{"BASE_URL", c.BaseURL},
+		{"ALLOWED_HOSTS", strings.Join(c.AllowedHosts, ",")},
+		{"TRUSTED_PROXIES", joinPrefixes(c.TrustedProxies)},
+		{"DATA_DIR", c.DataDir},
+		{"DATA_DIR_MODE", fmt.Sprintf("%#o", c.DataDirMode)},
+		{"DATABASE_PATH", c.DatabasePath},
+		{"LOG_LEVEL", c.LogLevel},
+		{"LOG_QUERY_STRINGS", strconv.FormatBool(c.LogQueryStrings)},
+		{"CSP_REPORT_ONLY", strconv.FormatBool(c.CSPReportOnly)},
+		{"TLS_CERT_FILE", c.TLSCertFile},
+		{"TLS_KEY_FILE", c.TLSKeyFile},
+		{"TLS_AUTOCERT_DOMAINS", strings.Join(c.TLSAutocertDomains, ",")},
+		{"REQUEST_TIMEOUT", c.RequestTimeout.String()},
+		{"READ_HEADER_TIMEOUT", c.ReadHeaderTimeout.String()},
+		{"MAINTENANCE_INTERVAL", c.MaintenanceInterval.String()},
+		{"WAL_CHECKPOINT_INTERVAL", c.CheckpointInterval.String()},
+		{"LOGIN_ATTEMPT_RETENTION", c.LoginAttemptRetention.String()},
+		{"COARSEN_LOGIN_IPS", strconv.FormatBool(c.CoarsenLoginIPs)},
+		{"SQLITE_BUSY_TIMEOUT_MS", strconv.Itoa(c.SQLiteBusyTimeoutMS)},
+		{"SQLITE_CACHE_SIZE", strconv.Itoa(c.SQLiteCacheSize)},
+		{"SQLITE_MMAP_SIZE", strconv.Itoa(c.SQLiteMmapSize)},
+		{"SQLITE_SYNCHRONOUS", c.SQLiteSynchronous},
+		{"WEBHOOK_URL", c.WebhookURL},
+	}
+	for _, secret := range append(c.secrets(),
+		namedSecret{"ENCRYPTION_SECRET_PREVIOUS", strings.Join(c.EncryptionSecretPrevious, ",")},
+		namedSecret{"HMAC_SECRET", c.HMACSecret},
+		namedSecret{"HMAC_PEPPER", c.HMACPepper},
+		namedSecret{"WEBHOOK_SECRET", c.WebhookSecret},
+	) {
+		settings = append(settings, [2]string{secret.name, redactSecret(secret.value)})
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, setting := range settings {
+		value := setting[1]
+		if value == "" {
+			value = "(unset)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", setting[0], value)
+	}
+	return tw.Flush()
+}
+
+func joinPrefixes(prefixes []netip.Prefix) string {
+	values := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		values[i] = prefix.String()
+	}
+	return strings.Join(values, ",")
+}
+
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return fmt.Sprintf("*** (%d characters)", len(secret))
+}
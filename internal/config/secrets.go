@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const minDistinctSecretChars = 10
+
+// fragments of the example values in docs and compose files; a secret
+// containing one was copied rather than generated
+var placeholderMarkers = []string{"changeme", "replaceme", "placeholder", "yoursecret", "randomstring"}
+
+type namedSecret struct {
+	name  string
+	value string
+}
+
+func (c *Config) secrets() []namedSecret {
+	return []namedSecret{
+		{"SESSION_SECRET", c.SessionSecret},
+		{"ENCRYPTION_SECRET", c.EncryptionSecret},
+		{"CSRF_KEY", c.CSRFKey},
+	}
+}
+
+func isPlaceholderSecret(secret string) bool {
+	var b strings.Builder
+	for _, r := range strings.ToLower(secret) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	normalized := b.String()
+	for _, marker := range placeholderMarkers {
+		if strings.Contains(normalized, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// SecretWarnings flags secrets that pass the length checks but look hand
+// typed rather than randomly generated
+func (c *Config) SecretWarnings() []string {
+	var warnings []string
+	for _, secret := range c.secrets() {
+		distinct := map[rune]bool{}
+		onlyLetters := true
+		for _, r := range secret.value {
+			distinct[r] = true
+			if !unicode.IsLetter(r) {
+				onlyLetters = false
+			}
+		}
+
+		switch {
+		case len(distinct) < minDistinctSecretChars:
+			warnings = append(warnings, fmt.Sprintf("%s uses fewer than %d distinct characters", secret.name, minDistinctSecretChars))
+		case onlyLetters:
+			warnings = append(warnings, fmt.Sprintf("%s contains only letters and may be made of dictionary words", secret.name))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// source resolves a setting from the real environment first, then a .env
+// file, then the config file. config file keys are the env var names in
+// lowercase, e.g. session_secret
+type source struct {
+	dotenv map[string]string
+	file   map[string]string
+	read   map[string]bool
+	// invalid collects settings that were set but couldn't be parsed, for
+	// Load to report alongside its own checks
+	invalid []string
+}
+
+func newSource(dotenvPath string) (*source, error) {
+	dotenv, err := readDotenv(dotenvPath)
+	if err != nil {
+		return nil, err
+	}
+	src := &source{dotenv: dotenv, file: map[string]string{}, read: map[string]bool{}}
+
+	path := src.env("CONFIG_FILE")
+	if path == "" {
+		return src, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var values map[string]string
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&values); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	for key, value := range values {
+		src.file[strings.ToUpper(key)] = value
+	}
+	return src, nil
+}
+
+// env reads the real environment, falling back to .env for unset variables
+func (s *source) env(key string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return s.dotenv[key]
+}
+
+func (s *source) get(key string) string {
+	s.read[key] = true
+	if v := s.env(key); v != "" {
+		return v
+	}
+	return s.file[key]
+}
+
+// checkUnused rejects file keys no setting asked for, so a typo in the file
+// fails loudly instead of silently keeping the default
+func (s *source) checkUnused() error {
+	var unknown []string
+	for key := range s.file {
+		if !s.read[key] {
+			unknown = append(unknown, strings.ToLower(key))
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return fmt.Errorf("unknown settings in config file: %v", unknown)
+	}
+	return nil
+}
+
+func (s *source) string(key, fallback string) string {
+	if v := s.get(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func (s *source) int(key string, fallback int) int {
+	if v := s.get(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func (s *source) bool(key string) bool {
+	v, _ := strconv.ParseBool(s.get(key))
+	return v
+}
+
+func (s *source) list(key string) []string {
+	var values []string
+	for _, v := range strings.Split(s.get(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func (s *source) duration(key string, fallback time.Duration) time.Duration {
+	v := s.get(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		s.invalid = append(s.invalid, key+" (must be a positive duration like 30s or 15m)")
+		return fallback
+	}
+	return d
+}
@@ -1,6 +1,9 @@
 package crypto
 
 import (
+	"encoding/base64"
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -71,8 +74,34 @@ func TestDecryptWithWrongKey(t *testing.T) {
 	encrypted, _ := enc1.Encrypt("secret data")
 
 	_, err := enc2.Decrypt(encrypted)
-	if err == nil {
-		t.Error("decryption with wrong key should fail")
+	if !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("expected ErrDecryptFailed with the wrong key, got %v", err)
+	}
+}
+
+func TestDecryptCorruptionErrors(t *testing.T) {
+	enc, _ := NewEncryptor(testKey())
+	encrypted, _ := enc.Encrypt("secret data")
+	raw, _ := base64.StdEncoding.DecodeString(encrypted)
+
+	flipped := append([]byte(nil), raw...)
+	flipped[len(flipped)-1] ^= 0x01
+
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{"not base64", "not*base64!", ErrBadEncoding},
+		{"empty", "", ErrCiphertextTooShort},
+		{"nonce only", base64.StdEncoding.EncodeToString(raw[:12]), ErrCiphertextTooShort},
+		{"truncated tag", base64.StdEncoding.EncodeToString(raw[:12+15]), ErrCiphertextTooShort},
+		{"flipped bit", base64.StdEncoding.EncodeToString(flipped), ErrDecryptFailed},
+	}
+	for _, tt := range tests {
+		if _, err := enc.Decrypt(tt.input); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
 	}
 }
 
@@ -83,6 +112,41 @@ func TestNewEncryptorInvalidKeyLength(t *testing.T) {
 	}
 }
 
+func TestEncryptorDecryptsWithPreviousKeys(t *testing.T) {
+	oldKey := []byte("different-key-exactly-32-bytes!!")
+	old, _ := NewEncryptor(oldKey)
+	legacy, _ := old.Encrypt("written before rotation")
+
+	rotated, err := NewEncryptor(testKey(), oldKey)
+	if err != nil {
+		t.Fatalf("NewEncryptor: %v", err)
+	}
+	if decrypted, err := rotated.Decrypt(legacy); err != nil || decrypted != "written before rotation" {
+		t.Errorf("expected old data to decrypt after rotation, got %q, %v", decrypted, err)
+	}
+
+	fresh, _ := rotated.Encrypt("written after rotation")
+	if _, err := old.Decrypt(fresh); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("expected new writes to use the new key, got %v", err)
+	}
+	current, _ := NewEncryptor(testKey())
+	if decrypted, err := current.Decrypt(fresh); err != nil || decrypted != "written after rotation" {
+		t.Errorf("expected the new key alone to decrypt new writes, got %q, %v", decrypted, err)
+	}
+
+	unrelated, _ := NewEncryptor([]byte("some-other-key-exactly-32-bytes!"))
+	stranger, _ := unrelated.Encrypt("neither key")
+	if _, err := rotated.Decrypt(stranger); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("expected ErrDecryptFailed when no key matches, got %v", err)
+	}
+}
+
+func TestNewEncryptorInvalidPreviousKey(t *testing.T) {
+	if _, err := NewEncryptor(testKey(), []byte("too-short")); err == nil {
+		t.Error("expected error for an invalid previous key")
+	}
+}
+
 func TestDeriveKeyProducesConsistentOutput(t *testing.T) {
 	salt := []byte("test-salt-16byte")
 	key1 := DeriveKey("my-secret", salt)
@@ -139,6 +203,24 @@ func TestGenerateSalt(t *testing.T) {
 	}
 }
 
+func TestConstantTimeEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"token", "token", true},
+		{"", "", true},
+		{"token", "tokeN", false},
+		{"token", "token-longer", false},
+		{"token", "", false},
+	}
+	for _, tt := range tests {
+		if got := ConstantTimeEqual(tt.a, tt.b); got != tt.want {
+			t.Errorf("ConstantTimeEqual(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
 func TestHMACDeterministic(t *testing.T) {
 	h := NewHMAC(testKey())
 
@@ -176,3 +258,148 @@ func TestHMACDifferentKeys(t *testing.T) {
 		t.Error("different keys should produce different HMAC hashes")
 	}
 }
+
+func TestPepperedHMAC(t *testing.T) {
+	plain := NewHMAC(testKey())
+	peppered := NewPepperedHMAC(testKey(), "install-pepper")
+
+	if peppered.Hash("test@example.com") == plain.Hash("test@example.com") {
+		t.Error("peppered hash should differ from the unpeppered hash")
+	}
+	if peppered.Hash("test@example.com") != NewPepperedHMAC(testKey(), "install-pepper").Hash("test@example.com") {
+		t.Error("peppered hash should be stable for the same key and pepper")
+	}
+	if NewPepperedHMAC(testKey(), "").Hash("test@example.com") != plain.Hash("test@example.com") {
+		t.Error("an empty pepper should keep the existing hashes")
+	}
+}
+
+func TestHMACHashN(t *testing.T) {
+	h := NewHMAC(testKey())
+
+	short, err := h.HashN("test@example.com", MinHashBytes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(short) != MinHashBytes*2 {
+		t.Errorf("expected %d hex chars, got %d", MinHashBytes*2, len(short))
+	}
+	again, _ := h.HashN("test@example.com", MinHashBytes)
+	if short != again {
+		t.Error("truncated hash should be deterministic")
+	}
+	if !strings.HasPrefix(h.Hash("test@example.com"), short) {
+		t.Error("truncated hash should be a prefix of the full hash")
+	}
+
+	for _, n := range []int{0, MinHashBytes - 1, 33} {
+		if _, err := h.HashN("test@example.com", n); err == nil {
+			t.Errorf("expected error for %d bytes", n)
+		}
+	}
+}
+
+func TestEncryptDeterministic(t *testing.T) {
+	enc, err := NewEncryptor(testKey())
+	if err != nil {
+		t.Fatalf("creating encryptor: %v", err)
+	}
+
+	first := enc.EncryptDeterministic("tag:respite")
+	if first != enc.EncryptDeterministic("tag:respite") {
+		t.Error("same plaintext should produce identical deterministic ciphertext")
+	}
+	if first == enc.EncryptDeterministic("tag:medication") {
+		t.Error("different plaintexts should produce different ciphertext")
+	}
+
+	decrypted, err := enc.Decrypt(first)
+	if err != nil {
+		t.Fatalf("decrypting: %v", err)
+	}
+	if decrypted != "tag:respite" {
+		t.Errorf("expected round trip, got %q", decrypted)
+	}
+
+	randomized, _ := enc.Encrypt("tag:respite")
+	if randomized == first {
+		t.Error("Encrypt should stay randomized")
+	}
+}
+
+func TestHouseholdKeysAreIsolated(t *testing.T) {
+	keyring, err := NewKeyring(testKey())
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	first, _ := keyring.ForHousehold("salt-one")
+	second, _ := keyring.ForHousehold("salt-two")
+
+	encrypted, _ := first.Encrypt("household one data")
+	if _, err := second.Decrypt(encrypted); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("expected another household's key to fail, got %v", err)
+	}
+	master, _ := keyring.ForHousehold("")
+	if _, err := master.Decrypt(encrypted); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("expected the master key to fail on household data, got %v", err)
+	}
+
+	again, _ := keyring.ForHousehold("salt-one")
+	if decrypted, err := again.Decrypt(encrypted); err != nil || decrypted != "household one data" {
+		t.Errorf("expected the same salt to decrypt, got %q, %v", decrypted, err)
+	}
+}
+
+func TestHouseholdKeyDependsOnMasterKey(t *testing.T) {
+	one, _ := DeriveHouseholdKey(testKey(), "salt")
+	two, _ := DeriveHouseholdKey([]byte("different-key-exactly-32-bytes!!"), "salt")
+	if string(one) == string(two) {
+		t.Error("expected different master keys to derive different household keys")
+	}
+}
+
+func TestReencryptMovesLegacyData(t *testing.T) {
+	keyring, _ := NewKeyring(testKey())
+	master, _ := keyring.ForHousehold("")
+	legacy, _ := master.Encrypt("written before household keys")
+
+	moved, err := keyring.Reencrypt(legacy, "salt-one")
+	if err != nil {
+		t.Fatalf("Reencrypt: %v", err)
+	}
+	household, _ := keyring.ForHousehold("salt-one")
+	if decrypted, err := household.Decrypt(moved); err != nil || decrypted != "written before household keys" {
+		t.Errorf("expected the household key to decrypt moved data, got %q, %v", decrypted, err)
+	}
+	if _, err := master.Decrypt(moved); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("expected moved data to no longer decrypt with the master key, got %v", err)
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	oldKey := testKey()
+	newKey := []byte("different-key-exactly-32-bytes!!")
+
+	before, _ := NewKeyring(oldKey)
+	oldHousehold, _ := before.ForHousehold("salt-one")
+	householdData, _ := oldHousehold.Encrypt("household data")
+	masterData, _ := before.Master().Encrypt("master data")
+
+	after, err := NewKeyring(newKey, oldKey)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	household, _ := after.ForHousehold("salt-one")
+	if decrypted, err := household.Decrypt(householdData); err != nil || decrypted != "household data" {
+		t.Errorf("expected the previous key to decrypt household data, got %q, %v", decrypted, err)
+	}
+	if decrypted, err := after.Master().Decrypt(masterData); err != nil || decrypted != "master data" {
+		t.Errorf("expected the previous key to decrypt master data, got %q, %v", decrypted, err)
+	}
+
+	// new writes use the new key only
+	fresh, _ := household.Encrypt("written after rotation")
+	if _, err := oldHousehold.Decrypt(fresh); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("expected data written after rotation to need the new key, got %v", err)
+	}
+}
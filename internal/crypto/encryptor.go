@@ -3,17 +3,61 @@ package crypto
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 )
 
+// Decrypt wraps one of these so callers can tell a wrong key from damaged data
+var (
+	// ErrBadEncoding means the stored value isn't base64 at all
+	ErrBadEncoding = errors.New("ciphertext is not valid base64")
+	// ErrCiphertextTooShort means the value can't hold a nonce and tag, so it
+	// was truncated or never encrypted
+	ErrCiphertextTooShort = errors.New("ciphertext too short")
+	// ErrDecryptFailed means authentication failed: the key is wrong or the
+	// ciphertext was altered, which gcm deliberately can't tell apart
+	ErrDecryptFailed = errors.New("decryption failed")
+)
+
 type Encryptor struct {
-	gcm cipher.AEAD
+	gcm      cipher.AEAD
+	previous []cipher.AEAD
+	nonceKey []byte
 }
 
-func NewEncryptor(key []byte) (*Encryptor, error) {
+// NewEncryptor encrypts with key. previous keys are only tried by Decrypt,
+// so a key can be rotated without a maintenance window: values written
+// under an old key stay readable and move to the new one the next time
+// they are written. gcm authentication already tells the keys apart, so
+// stored values carry no key id. deterministic ciphertext changes with the
+// key, so equality lookups only find values rewritten since the rotation
+func NewEncryptor(key []byte, previous ...[]byte) (*Encryptor, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	enc := &Encryptor{gcm: gcm}
+	for _, old := range previous {
+		oldGCM, err := newGCM(old)
+		if err != nil {
+			return nil, fmt.Errorf("previous key: %w", err)
+		}
+		enc.previous = append(enc.previous, oldGCM)
+	}
+	// a separate key for deterministic nonces so they reveal nothing about
+	// the encryption key itself
+	nonceMAC := hmac.New(sha256.New, key)
+	nonceMAC.Write([]byte("deterministic-nonce"))
+	enc.nonceKey = nonceMAC.Sum(nil)
+	return enc, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
 	if len(key) != 32 {
 		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
 	}
@@ -25,7 +69,7 @@ func NewEncryptor(key []byte) (*Encryptor, error) {
 	if err != nil {
 		return nil, fmt.Errorf("creating gcm: %w", err)
 	}
-	return &Encryptor{gcm: gcm}, nil
+	return gcm, nil
 }
 
 func (e *Encryptor) Encrypt(plaintext string) (string, error) {
@@ -37,19 +81,37 @@ func (e *Encryptor) Encrypt(plaintext string) (string, error) {
 	return base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
+// EncryptDeterministic derives the nonce from the plaintext, so equal values
+// produce equal ciphertext and can be matched with a plain equality lookup.
+// that also reveals which rows share a value, so only use it for fields that
+// need searching; Encrypt stays the default. output decrypts with Decrypt
+func (e *Encryptor) EncryptDeterministic(plaintext string) string {
+	mac := hmac.New(sha256.New, e.nonceKey)
+	mac.Write([]byte(plaintext))
+	nonce := mac.Sum(nil)[:e.gcm.NonceSize()]
+	ciphertext := e.gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(ciphertext)
+}
+
 func (e *Encryptor) Decrypt(encoded string) (string, error) {
 	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
-		return "", fmt.Errorf("decoding base64: %w", err)
+		return "", fmt.Errorf("%w: %v", ErrBadEncoding, err)
 	}
 	nonceSize := e.gcm.NonceSize()
-	if len(ciphertext) < nonceSize {
-		return "", fmt.Errorf("ciphertext too short")
+	if len(ciphertext) < nonceSize+e.gcm.Overhead() {
+		return "", ErrCiphertextTooShort
 	}
 	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
 	plaintext, err := e.gcm.Open(nil, nonce, ciphertext, nil)
+	for _, old := range e.previous {
+		if err == nil {
+			break
+		}
+		plaintext, err = old.Open(nil, nonce, ciphertext, nil)
+	}
 	if err != nil {
-		return "", fmt.Errorf("decrypting: %w", err)
+		return "", fmt.Errorf("%w: %v", ErrDecryptFailed, err)
 	}
 	return string(plaintext), nil
 }
@@ -4,8 +4,12 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 )
 
+// MinHashBytes keeps truncated hashes at 128 bits
+const MinHashBytes = 16
+
 type HMACHasher struct {
 	key []byte
 }
@@ -15,7 +19,33 @@ func NewHMAC(key []byte) *HMACHasher {
 }
 
 func (h *HMACHasher) Hash(plaintext string) string {
+	return hex.EncodeToString(h.sum(plaintext))
+}
+
+// HashN returns the first n bytes of the hash as hex, for new columns that
+// want smaller indexes. n below MinHashBytes is refused because short
+// prefixes start to collide across large tables
+func (h *HMACHasher) HashN(plaintext string, n int) (string, error) {
+	if n < MinHashBytes || n > sha256.Size {
+		return "", fmt.Errorf("hash length must be between %d and %d bytes, got %d", MinHashBytes, sha256.Size, n)
+	}
+	return hex.EncodeToString(h.sum(plaintext)[:n]), nil
+}
+
+func (h *HMACHasher) sum(plaintext string) []byte {
 	mac := hmac.New(sha256.New, h.key)
 	mac.Write([]byte(plaintext))
-	return hex.EncodeToString(mac.Sum(nil))
+	return mac.Sum(nil)
+}
+
+// NewPepperedHMAC mixes pepper into key. the pepper lives outside the
+// database, so a copy of the database plus its salt is not enough to confirm
+// guessed emails. an empty pepper gives the same hashes as NewHMAC
+func NewPepperedHMAC(key []byte, pepper string) *HMACHasher {
+	if pepper == "" {
+		return NewHMAC(key)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(pepper))
+	return NewHMAC(mac.Sum(nil))
 }
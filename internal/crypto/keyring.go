@@ -0,0 +1,96 @@
+package crypto
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+)
+
+// DeriveHouseholdKey derives a household's data key from the master key and
+// the household's own salt. the master key is already stretched by
+// DeriveKey, so a plain hkdf is enough and keeps per-request lookups cheap
+func DeriveHouseholdKey(masterKey []byte, salt string) ([]byte, error) {
+	key, err := hkdf.Key(sha256.New, masterKey, []byte(salt), "shelterkin-household-data-key", 32)
+	if err != nil {
+		return nil, fmt.Errorf("deriving household key: %w", err)
+	}
+	return key, nil
+}
+
+// Keyring hands out one encryptor per household so a leaked data key only
+// exposes that household. households stored before per-household keys have
+// an empty salt and keep using the master key until they are re-encrypted
+type Keyring struct {
+	masterKey    []byte
+	previousKeys [][]byte
+	master       *Encryptor
+
+	mu         sync.Mutex
+	households map[string]*Encryptor
+}
+
+// NewKeyring encrypts with masterKey. previous master keys still decrypt,
+// and household keys derived from them do too, so rotating the master key
+// leaves existing rows readable
+func NewKeyring(masterKey []byte, previous ...[]byte) (*Keyring, error) {
+	master, err := NewEncryptor(masterKey, previous...)
+	if err != nil {
+		return nil, err
+	}
+	return &Keyring{
+		masterKey:    masterKey,
+		previousKeys: previous,
+		master:       master,
+		households:   make(map[string]*Encryptor),
+	}, nil
+}
+
+// Master returns the encryptor for data that isn't tied to one household
+func (k *Keyring) Master() *Encryptor {
+	return k.master
+}
+
+// ForHousehold returns the encryptor for the household with this salt
+func (k *Keyring) ForHousehold(salt string) (*Encryptor, error) {
+	if salt == "" {
+		return k.master, nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if enc, ok := k.households[salt]; ok {
+		return enc, nil
+	}
+	key, err := DeriveHouseholdKey(k.masterKey, salt)
+	if err != nil {
+		return nil, err
+	}
+	previous := make([][]byte, len(k.previousKeys))
+	for i, old := range k.previousKeys {
+		if previous[i], err = DeriveHouseholdKey(old, salt); err != nil {
+			return nil, err
+		}
+	}
+	enc, err := NewEncryptor(key, previous...)
+	if err != nil {
+		return nil, err
+	}
+	k.households[salt] = enc
+	return enc, nil
+}
+
+// Reencrypt moves one value from the master key to a household key, for
+// migrating rows written before the household had its own salt. columns
+// written with EncryptDeterministic must be rewritten the same way instead
+func (k *Keyring) Reencrypt(encoded, salt string) (string, error) {
+	plaintext, err := k.master.Decrypt(encoded)
+	if err != nil {
+		return "", err
+	}
+	enc, err := k.ForHousehold(salt)
+	if err != nil {
+		return "", err
+	}
+	return enc.Encrypt(plaintext)
+}
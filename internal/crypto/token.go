@@ -0,0 +1,24 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+)
+
+// GenerateToken returns 32 random bytes encoded for use in URLs
+func GenerateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// ConstantTimeEqual compares tokens, signatures and hashes without leaking
+// how many leading bytes matched. only the lengths can be told apart, and
+// everything compared here has a fixed length
+func ConstantTimeEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
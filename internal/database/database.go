@@ -1,15 +1,70 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pressly/goose/v3"
 	_ "modernc.org/sqlite"
 )
 
-func Open(databasePath string) (*sql.DB, error) {
+const (
+	readPoolSize         = 4
+	defaultBusyTimeoutMS = 5000
+)
+
+// TimestampFormat matches the strftime('%Y-%m-%dT%H:%M:%SZ', 'now') column defaults
+const TimestampFormat = "2006-01-02T15:04:05Z"
+
+// Options tunes per-connection pragmas. zero values leave sqlite's own
+// defaults in place, except BusyTimeoutMS which falls back to 5 seconds
+type Options struct {
+	BusyTimeoutMS int
+	CacheSize     int
+	MmapSize      int
+	Synchronous   string
+}
+
+type pragma struct {
+	name  string
+	value string
+}
+
+func (o Options) pragmas() []pragma {
+	busyTimeout := o.BusyTimeoutMS
+	if busyTimeout == 0 {
+		busyTimeout = defaultBusyTimeoutMS
+	}
+
+	pragmas := []pragma{{"busy_timeout", strconv.Itoa(busyTimeout)}}
+	if o.CacheSize != 0 {
+		pragmas = append(pragmas, pragma{"cache_size", strconv.Itoa(o.CacheSize)})
+	}
+	if o.MmapSize != 0 {
+		pragmas = append(pragmas, pragma{"mmap_size", strconv.Itoa(o.MmapSize)})
+	}
+	if o.Synchronous != "" {
+		pragmas = append(pragmas, pragma{"synchronous", o.Synchronous})
+	}
+	return pragmas
+}
+
+func Open(databasePath string, opts Options) (*sql.DB, error) {
+	if err := createPrivate(databasePath); err != nil {
+		return nil, err
+	}
+
 	db, err := sql.Open("sqlite", databasePath)
 	if err != nil {
 		return nil, fmt.Errorf("opening database: %w", err)
@@ -18,15 +73,12 @@ func Open(databasePath string) (*sql.DB, error) {
 	// sqlite only supports a single writer at a time
 	db.SetMaxOpenConns(1)
 
-	pragmas := []string{
-		"PRAGMA journal_mode = WAL",
-		"PRAGMA busy_timeout = 5000",
-		"PRAGMA foreign_keys = ON",
-	}
-	for _, pragma := range pragmas {
-		if _, err := db.Exec(pragma); err != nil {
+	pragmas := append([]pragma{{"journal_mode", "WAL"}, {"foreign_keys", "ON"}}, opts.pragmas()...)
+	for _, p := range pragmas {
+		stmt := fmt.Sprintf("PRAGMA %s = %s", p.name, p.value)
+		if _, err := db.Exec(stmt); err != nil {
 			db.Close()
-			return nil, fmt.Errorf("executing %s: %w", pragma, err)
+			return nil, fmt.Errorf("executing %s: %w", stmt, err)
 		}
 	}
 
@@ -38,6 +90,96 @@ func Open(databasePath string) (*sql.DB, error) {
 	return db, nil
 }
 
+// createPrivate makes sure the database file exists with mode 0600 before
+// sqlite opens it. sqlite would otherwise create it under the process umask,
+// often world-readable, and it gives the -wal and -shm files it creates the
+// same mode as the database
+func createPrivate(databasePath string) error {
+	if databasePath == ":memory:" || strings.HasPrefix(databasePath, "file:") {
+		return nil
+	}
+
+	f, err := os.OpenFile(databasePath, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("creating database file: %w", err)
+	}
+	f.Close()
+
+	// files left by an older version, or a restored backup, may be wider
+	for _, file := range []string{databasePath, databasePath + "-wal", databasePath + "-shm"} {
+		if err := os.Chmod(file, 0600); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("restricting %s: %w", filepath.Base(file), err)
+		}
+	}
+	return nil
+}
+
+// OpenPair opens the single-writer handle plus a read-only handle with a
+// larger pool, so WAL readers aren't queued behind the one write connection
+func OpenPair(databasePath string, opts Options) (db, readDB *sql.DB, err error) {
+	db, err = Open(databasePath, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	readDB, err = OpenReadOnly(databasePath, opts)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	return db, readDB, nil
+}
+
+// OpenReadOnly opens a query-only handle on an existing database. unlike
+// Open it never creates the file, so a wrong path fails instead of leaving
+// an empty database behind
+func OpenReadOnly(databasePath string, opts Options) (*sql.DB, error) {
+	// pragmas set via db.Exec only reach one pooled connection, so the read
+	// handle carries them in the dsn and every new connection applies them
+	params := url.Values{"mode": {"ro"}}
+	for _, p := range append(opts.pragmas(), pragma{"query_only", "1"}) {
+		params.Add("_pragma", fmt.Sprintf("%s(%s)", p.name, p.value))
+	}
+	readDB, err := sql.Open("sqlite", "file:"+databasePath+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("opening read-only database: %w", err)
+	}
+	readDB.SetMaxOpenConns(readPoolSize)
+
+	if err := readDB.Ping(); err != nil {
+		readDB.Close()
+		return nil, fmt.Errorf("pinging read-only database: %w", err)
+	}
+
+	return readDB, nil
+}
+
+// Optimize lets sqlite refresh statistics for tables whose query plans would
+// benefit, cheap enough to run on every startup
+func Optimize(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, "PRAGMA optimize"); err != nil {
+		return fmt.Errorf("optimizing database: %w", err)
+	}
+	return nil
+}
+
+type CheckpointResult struct {
+	Busy         bool
+	LogFrames    int
+	Checkpointed int
+}
+
+// Checkpoint copies the WAL into the main database file and truncates it.
+// run it on the single-writer handle so it queues behind other writes
+func Checkpoint(ctx context.Context, db *sql.DB) (CheckpointResult, error) {
+	var result CheckpointResult
+	if err := db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&result.Busy, &result.LogFrames, &result.Checkpointed); err != nil {
+		return CheckpointResult{}, fmt.Errorf("checkpointing wal: %w", err)
+	}
+	return result, nil
+}
+
 func RunMigrations(db *sql.DB, migrationsFS embed.FS, dir string) error {
 	goose.SetBaseFS(migrationsFS)
 	if err := goose.SetDialect("sqlite3"); err != nil {
@@ -48,3 +190,90 @@ func RunMigrations(db *sql.DB, migrationsFS embed.FS, dir string) error {
 	}
 	return nil
 }
+
+type MigrationStatus struct {
+	Version   int64
+	Source    string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// MigrationStatuses lists every embedded migration and whether it has been
+// applied to db
+func MigrationStatuses(ctx context.Context, db *sql.DB, migrationsFS fs.FS, dir string) ([]MigrationStatus, error) {
+	provider, err := newMigrationProvider(db, migrationsFS, dir)
+	if err != nil {
+		return nil, err
+	}
+	results, err := provider.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading migration status: %w", err)
+	}
+
+	statuses := make([]MigrationStatus, 0, len(results))
+	for _, result := range results {
+		statuses = append(statuses, MigrationStatus{
+			Version:   result.Source.Version,
+			Source:    path.Base(result.Source.Path),
+			Applied:   result.State == goose.StateApplied,
+			AppliedAt: result.AppliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// RollbackMigration reverts the most recently applied migration and returns
+// its version
+func RollbackMigration(ctx context.Context, db *sql.DB, migrationsFS fs.FS, dir string) (int64, error) {
+	provider, err := newMigrationProvider(db, migrationsFS, dir)
+	if err != nil {
+		return 0, err
+	}
+	result, err := provider.Down(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("rolling back migration: %w", err)
+	}
+	return result.Source.Version, nil
+}
+
+func newMigrationProvider(db *sql.DB, migrationsFS fs.FS, dir string) (*goose.Provider, error) {
+	sub, err := fs.Sub(migrationsFS, dir)
+	if err != nil {
+		return nil, fmt.Errorf("opening migrations directory: %w", err)
+	}
+	provider, err := goose.NewProvider(goose.DialectSQLite3, db, sub)
+	if err != nil {
+		return nil, fmt.Errorf("loading migrations: %w", err)
+	}
+	return provider, nil
+}
+
+// Backup writes a consistent snapshot of the database at databasePath to dst
+// with VACUUM INTO. it runs on its own connection, which is just another WAL
+// reader, so the app's single writer keeps working while the copy is made.
+// the snapshot folds the WAL into one self-contained file. dst must not exist
+// and is created 0600
+func Backup(ctx context.Context, databasePath, dst string) error {
+	db, err := sql.Open("sqlite", databasePath)
+	if err != nil {
+		return fmt.Errorf("opening database for backup: %w", err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, "PRAGMA busy_timeout = 5000"); err != nil {
+		return fmt.Errorf("setting busy timeout: %w", err)
+	}
+	// the snapshot holds everything the live database does, so it gets the
+	// same 0600. VACUUM INTO accepts an existing file as long as it is empty
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("creating backup file: %w", err)
+	}
+	f.Close()
+
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", dst); err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("backing up database: %w", err)
+	}
+	return nil
+}
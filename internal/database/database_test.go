@@ -0,0 +1,234 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOpenPairReadsProceedDuringWrite(t *testing.T) {
+	db, readDB, err := OpenPair(filepath.Join(t.TempDir(), "test.db"), Options{})
+	if err != nil {
+		t.Fatalf("opening pair: %v", err)
+	}
+	defer db.Close()
+	defer readDB.Close()
+
+	if _, err := db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatalf("creating table: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO items (id) VALUES (1)`); err != nil {
+		t.Fatalf("seeding: %v", err)
+	}
+
+	// hold the write lock with an uncommitted insert
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("beginning write: %v", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`INSERT INTO items (id) VALUES (2)`); err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, readPoolSize)
+	counts := make(chan int, readPoolSize)
+	for range readPoolSize {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := readDB.Conn(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer conn.Close()
+			var n int
+			if err := conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM items`).Scan(&n); err != nil {
+				errs <- err
+				return
+			}
+			counts <- n
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	close(counts)
+
+	for err := range errs {
+		t.Errorf("read blocked by write: %v", err)
+	}
+	for n := range counts {
+		if n != 1 {
+			t.Errorf("expected readers to see only committed rows, got %d", n)
+		}
+	}
+}
+
+func TestOpenPairReadHandleRejectsWrites(t *testing.T) {
+	db, readDB, err := OpenPair(filepath.Join(t.TempDir(), "test.db"), Options{})
+	if err != nil {
+		t.Fatalf("opening pair: %v", err)
+	}
+	defer db.Close()
+	defer readDB.Close()
+
+	if _, err := db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatalf("creating table: %v", err)
+	}
+	if _, err := readDB.Exec(`INSERT INTO items (id) VALUES (1)`); err == nil {
+		t.Error("expected the read handle to refuse writes")
+	}
+}
+
+func TestBackupProducesQueryableCopy(t *testing.T) {
+	dir := t.TempDir()
+	livePath := filepath.Join(dir, "live.db")
+	db, err := Open(livePath, Options{})
+	if err != nil {
+		t.Fatalf("opening: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)`); err != nil {
+		t.Fatalf("creating table: %v", err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if _, err := db.Exec(`INSERT INTO items (name) VALUES (?)`, name); err != nil {
+			t.Fatalf("seeding: %v", err)
+		}
+	}
+
+	// an open write must neither block the backup nor leak into it
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("beginning write: %v", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`INSERT INTO items (name) VALUES ('uncommitted')`); err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+
+	dst := filepath.Join(dir, "backup.db")
+	if err := Backup(context.Background(), livePath, dst); err != nil {
+		t.Fatalf("backing up: %v", err)
+	}
+
+	backup, err := Open(dst, Options{})
+	if err != nil {
+		t.Fatalf("opening backup: %v", err)
+	}
+	defer backup.Close()
+
+	var result string
+	if err := backup.QueryRow(`PRAGMA integrity_check`).Scan(&result); err != nil || result != "ok" {
+		t.Fatalf("expected a valid backup, got %q (%v)", result, err)
+	}
+	var n int
+	if err := backup.QueryRow(`SELECT COUNT(*) FROM items`).Scan(&n); err != nil {
+		t.Fatalf("querying backup: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("expected the 3 committed rows in backup, got %d", n)
+	}
+}
+
+func TestOpenAppliesCustomPragmas(t *testing.T) {
+	opts := Options{BusyTimeoutMS: 12000, CacheSize: -4096, Synchronous: "NORMAL"}
+	db, readDB, err := OpenPair(filepath.Join(t.TempDir(), "test.db"), opts)
+	if err != nil {
+		t.Fatalf("opening pair: %v", err)
+	}
+	defer db.Close()
+	defer readDB.Close()
+
+	for name, handle := range map[string]*sql.DB{"write": db, "read": readDB} {
+		var busyTimeout, cacheSize, synchronous int
+		if err := handle.QueryRow("PRAGMA busy_timeout").Scan(&busyTimeout); err != nil {
+			t.Fatalf("%s: querying busy_timeout: %v", name, err)
+		}
+		if err := handle.QueryRow("PRAGMA cache_size").Scan(&cacheSize); err != nil {
+			t.Fatalf("%s: querying cache_size: %v", name, err)
+		}
+		if err := handle.QueryRow("PRAGMA synchronous").Scan(&synchronous); err != nil {
+			t.Fatalf("%s: querying synchronous: %v", name, err)
+		}
+		if busyTimeout != 12000 {
+			t.Errorf("%s: expected busy_timeout 12000, got %d", name, busyTimeout)
+		}
+		if cacheSize != -4096 {
+			t.Errorf("%s: expected cache_size -4096, got %d", name, cacheSize)
+		}
+		// NORMAL is 1
+		if synchronous != 1 {
+			t.Errorf("%s: expected synchronous NORMAL, got %d", name, synchronous)
+		}
+	}
+}
+
+func TestOpenDefaultBusyTimeout(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"), Options{})
+	if err != nil {
+		t.Fatalf("opening: %v", err)
+	}
+	defer db.Close()
+
+	var busyTimeout int
+	if err := db.QueryRow("PRAGMA busy_timeout").Scan(&busyTimeout); err != nil {
+		t.Fatalf("querying busy_timeout: %v", err)
+	}
+	if busyTimeout != 5000 {
+		t.Errorf("expected default busy_timeout 5000, got %d", busyTimeout)
+	}
+}
+
+func TestCheckpointTruncatesWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("opening: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, body TEXT)`); err != nil {
+		t.Fatalf("creating table: %v", err)
+	}
+	for range 500 {
+		if _, err := db.Exec(`INSERT INTO items (body) VALUES (?)`, strings.Repeat("x", 512)); err != nil {
+			t.Fatalf("inserting: %v", err)
+		}
+	}
+
+	before, err := os.Stat(path + "-wal")
+	if err != nil {
+		t.Fatalf("stat wal: %v", err)
+	}
+	if before.Size() == 0 {
+		t.Fatal("expected bulk inserts to grow the wal")
+	}
+
+	result, err := Checkpoint(context.Background(), db)
+	if err != nil {
+		t.Fatalf("checkpointing: %v", err)
+	}
+	if result.Busy {
+		t.Error("expected checkpoint not to be blocked")
+	}
+
+	after, err := os.Stat(path + "-wal")
+	if err != nil {
+		t.Fatalf("stat wal: %v", err)
+	}
+	if after.Size() != 0 {
+		t.Errorf("expected truncated wal, got %d bytes (was %d)", after.Size(), before.Size())
+	}
+}
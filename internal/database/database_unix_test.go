@@ -0,0 +1,73 @@
+//go:build unix
+
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestOpenCreatesPrivateFilesUnderPermissiveUmask(t *testing.T) {
+	// a umask of 0 would leave sqlite's own 0644 default world-readable
+	old := syscall.Umask(0)
+	t.Cleanup(func() { syscall.Umask(old) })
+
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("opening: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE t (x INTEGER)"); err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+
+	for _, file := range []string{path, path + "-wal"} {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatalf("stat %s: %v", file, err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("expected %s to be 0600, got %#o", filepath.Base(file), perm)
+		}
+	}
+
+	backup := filepath.Join(filepath.Dir(path), "backup.db")
+	if err := Backup(context.Background(), path, backup); err != nil {
+		t.Fatalf("backing up: %v", err)
+	}
+	info, err := os.Stat(backup)
+	if err != nil {
+		t.Fatalf("stat backup: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected the backup to be 0600, got %#o", perm)
+	}
+}
+
+func TestOpenTightensExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("opening: %v", err)
+	}
+	db.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected an existing database to be tightened to 0600, got %#o", perm)
+	}
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// ErrSchemaDrift means tables or columns the migrations create are missing,
+// usually because someone altered the database by hand
+var ErrSchemaDrift = errors.New("database schema does not match its migrations")
+
+// EnsureSchema checks that db has every table and column the migrations in
+// migrationsFS produce, so a hand-edited database fails at startup rather
+// than on whichever query first touches the missing piece. the expected
+// schema comes from applying the same migrations to an in-memory database,
+// so it never needs updating alongside them. extra tables and columns are
+// allowed
+func EnsureSchema(ctx context.Context, db *sql.DB, migrationsFS fs.FS, dir string) error {
+	ref, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return fmt.Errorf("opening reference database: %w", err)
+	}
+	defer ref.Close()
+	// every connection to :memory: is its own empty database
+	ref.SetMaxOpenConns(1)
+
+	provider, err := newMigrationProvider(ref, migrationsFS, dir)
+	if err != nil {
+		return err
+	}
+	if _, err := provider.Up(ctx); err != nil {
+		return fmt.Errorf("building reference schema: %w", err)
+	}
+
+	want, err := schemaColumns(ctx, ref)
+	if err != nil {
+		return err
+	}
+	have, err := schemaColumns(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for table, columns := range want {
+		if _, ok := have[table]; !ok {
+			missing = append(missing, "table "+table)
+			continue
+		}
+		for column := range columns {
+			if !have[table][column] {
+				missing = append(missing, "column "+table+"."+column)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("%w, missing %s", ErrSchemaDrift, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func schemaColumns(ctx context.Context, db *sql.DB) (map[string]map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT m.name, p.name FROM sqlite_master m, pragma_table_info(m.name) p
+		WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%'`)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema: %w", err)
+	}
+	defer rows.Close()
+
+	tables := make(map[string]map[string]bool)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, fmt.Errorf("scanning schema: %w", err)
+		}
+		if tables[table] == nil {
+			tables[table] = make(map[string]bool)
+		}
+		tables[table][column] = true
+	}
+	return tables, rows.Err()
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var schemaTestMigrations = fstest.MapFS{
+	"migrations/001_init.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE households (id TEXT PRIMARY KEY, name_enc TEXT NOT NULL);
+CREATE TABLE users (id TEXT PRIMARY KEY, household_id TEXT NOT NULL);
+
+-- +goose Down
+DROP TABLE users;
+DROP TABLE households;
+`)},
+	"migrations/002_user_email.sql": {Data: []byte(`-- +goose Up
+ALTER TABLE users ADD COLUMN email_enc TEXT;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN email_enc;
+`)},
+}
+
+func migratedSchemaDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"), Options{})
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	provider, err := newMigrationProvider(db, schemaTestMigrations, "migrations")
+	if err != nil {
+		t.Fatalf("loading migrations: %v", err)
+	}
+	if _, err := provider.Up(context.Background()); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	return db
+}
+
+func TestEnsureSchemaPassesOnMigratedDatabase(t *testing.T) {
+	db := migratedSchemaDB(t)
+	// extra objects an operator added are tolerated
+	if _, err := db.Exec(`CREATE TABLE notes (id TEXT)`); err != nil {
+		t.Fatalf("adding extra table: %v", err)
+	}
+
+	if err := EnsureSchema(context.Background(), db, schemaTestMigrations, "migrations"); err != nil {
+		t.Errorf("expected a complete schema to pass, got %v", err)
+	}
+}
+
+func TestEnsureSchemaReportsMissingTable(t *testing.T) {
+	db := migratedSchemaDB(t)
+	if _, err := db.Exec(`DROP TABLE users`); err != nil {
+		t.Fatalf("dropping table: %v", err)
+	}
+
+	err := EnsureSchema(context.Background(), db, schemaTestMigrations, "migrations")
+	if !errors.Is(err, ErrSchemaDrift) || !strings.Contains(err.Error(), "table users") {
+		t.Errorf("expected drift naming the users table, got %v", err)
+	}
+}
+
+func TestEnsureSchemaReportsMissingColumn(t *testing.T) {
+	db := migratedSchemaDB(t)
+	if _, err := db.Exec(`ALTER TABLE users DROP COLUMN email_enc`); err != nil {
+		t.Fatalf("dropping column: %v", err)
+	}
+
+	err := EnsureSchema(context.Background(), db, schemaTestMigrations, "migrations")
+	if !errors.Is(err, ErrSchemaDrift) || !strings.Contains(err.Error(), "column users.email_enc") {
+		t.Errorf("expected drift naming users.email_enc, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+// Package downloadtoken signs short-lived links to exports and backups, so
+// the file fetch can be a plain GET that still knows who may have it
+package downloadtoken
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/clock"
+	"github.com/shelterkin/shelterkin/internal/crypto"
+)
+
+// purpose is mixed into the signature so a token signed for some other use of
+// the same key can never pass as a download token
+const purpose = "download:"
+
+var (
+	ErrInvalid   = errors.New("download link is invalid")
+	ErrExpired   = errors.New("download link has expired")
+	ErrWrongUser = errors.New("download link belongs to another user")
+)
+
+type claims struct {
+	Resource string `json:"resource"`
+	UserID   string `json:"user"`
+	Expires  int64  `json:"expires"`
+}
+
+type Signer struct {
+	signer *crypto.HMACHasher
+	clock  clock.Clock
+}
+
+func NewSigner(signer *crypto.HMACHasher, clk clock.Clock) *Signer {
+	return &Signer{signer: signer, clock: clk}
+}
+
+// Issue returns a token that lets userID fetch resource until ttl runs out
+func (s *Signer) Issue(resource, userID string, ttl time.Duration) string {
+	data, _ := json.Marshal(claims{Resource: resource, UserID: userID, Expires: s.clock.Now().Add(ttl).Unix()})
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + s.signer.Hash(purpose+payload)
+}
+
+// Verify returns the resource token grants to userID. the user check means a
+// leaked link is useless to anyone but the person it was issued to
+func (s *Signer) Verify(token, userID string) (string, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || !crypto.ConstantTimeEqual(sig, s.signer.Hash(purpose+payload)) {
+		return "", ErrInvalid
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", ErrInvalid
+	}
+	var c claims
+	if err := json.Unmarshal(data, &c); err != nil || c.Resource == "" {
+		return "", ErrInvalid
+	}
+	if s.clock.Now().Unix() > c.Expires {
+		return "", ErrExpired
+	}
+	if !crypto.ConstantTimeEqual(c.UserID, userID) {
+		return "", ErrWrongUser
+	}
+	return c.Resource, nil
+}
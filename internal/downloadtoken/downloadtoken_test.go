@@ -0,0 +1,64 @@
+package downloadtoken
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/testutil"
+)
+
+func newSigner(t *testing.T) (*Signer, *testutil.FakeClock) {
+	t.Helper()
+	clk := testutil.NewFakeClock(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	return NewSigner(testutil.NewTestHMAC(t), clk), clk
+}
+
+func TestVerifyValidToken(t *testing.T) {
+	s, _ := newSigner(t)
+	token := s.Issue("export/u1.json", "u1", 5*time.Minute)
+
+	resource, err := s.Verify(token, "u1")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if resource != "export/u1.json" {
+		t.Errorf("expected export/u1.json, got %q", resource)
+	}
+}
+
+func TestVerifyExpiredToken(t *testing.T) {
+	s, clk := newSigner(t)
+	token := s.Issue("export/u1.json", "u1", 5*time.Minute)
+
+	clk.Advance(5*time.Minute + time.Second)
+	if _, err := s.Verify(token, "u1"); !errors.Is(err, ErrExpired) {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+}
+
+func TestVerifyRejectsOtherUser(t *testing.T) {
+	s, _ := newSigner(t)
+	token := s.Issue("export/u1.json", "u1", 5*time.Minute)
+
+	if _, err := s.Verify(token, "u2"); !errors.Is(err, ErrWrongUser) {
+		t.Errorf("expected ErrWrongUser, got %v", err)
+	}
+}
+
+func TestVerifyRejectsTamperedToken(t *testing.T) {
+	s, _ := newSigner(t)
+	other := s.Issue("export/u2.json", "u2", 5*time.Minute)
+	token := s.Issue("export/u1.json", "u1", 5*time.Minute)
+
+	for name, tampered := range map[string]string{
+		"empty":         "",
+		"no signature":  "abc",
+		"swapped claim": other[:len(other)-64] + token[len(token)-64:],
+		"bad signature": token + "0",
+	} {
+		if _, err := s.Verify(tampered, "u1"); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected ErrInvalid, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+// Package emailaddr normalizes email addresses the same way everywhere they
+// are hashed, so a lookup by hash finds what another package stored
+package emailaddr
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is the longest address SMTP can deliver to
+const MaxLength = 254
+
+// Normalize composes unicode to NFC before hashing so an address typed with
+// combining accents matches the same address typed precomposed
+func Normalize(email string) string {
+	return norm.NFC.String(strings.ToLower(strings.TrimSpace(email)))
+}
+
+// Problem describes what is wrong with a normalized address for the form
+// to show, or returns "" when it is acceptable
+func Problem(email string) string {
+	switch {
+	case !strings.Contains(email, "@"):
+		return "Enter a valid email address"
+	case utf8.RuneCountInString(email) > MaxLength:
+		return fmt.Sprintf("Email must be at most %d characters", MaxLength)
+	}
+	return ""
+}
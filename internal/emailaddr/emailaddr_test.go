@@ -0,0 +1,26 @@
+package emailaddr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	// "e" followed by a combining acute accent, then the precomposed form
+	if Normalize(" Rene\u0301@Example.com ") != "ren\u00e9@example.com" {
+		t.Error("expected case, whitespace and unicode composition to be normalized")
+	}
+}
+
+func TestProblem(t *testing.T) {
+	for email, wantProblem := range map[string]bool{
+		"carer@example.com": false,
+		"carer.example.com": true,
+		"":                  true,
+		strings.Repeat("a", MaxLength) + "@example.com": true,
+	} {
+		if got := Problem(email) != ""; got != wantProblem {
+			t.Errorf("Problem(%q) reported a problem = %v, want %v", email, got, wantProblem)
+		}
+	}
+}
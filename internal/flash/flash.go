@@ -0,0 +1,90 @@
+// Package flash carries a one-time message across a redirect in a signed,
+// short-lived cookie
+package flash
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/crypto"
+)
+
+const (
+	cookieName = "shelterkin_flash"
+	maxAge     = time.Minute
+)
+
+type Message struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	Expires int64  `json:"expires"`
+}
+
+// signaturePurpose keeps flash signatures apart from the email and token
+// lookup hashes made with the same key
+const signaturePurpose = "flash:"
+
+type Store struct {
+	signer *crypto.HMACHasher
+	secure bool
+}
+
+// NewStore marks the cookie Secure when secure is set. pass
+// cfg.SecureCookies(), since browsers drop Secure cookies over plain http
+func NewStore(signer *crypto.HMACHasher, secure bool) *Store {
+	return &Store{signer: signer, secure: secure}
+}
+
+// Set queues a message for the next page the browser loads
+func (s *Store) Set(w http.ResponseWriter, level, message string) {
+	data, _ := json.Marshal(Message{Level: level, Message: message, Expires: time.Now().Add(maxAge).Unix()})
+	payload := base64.RawURLEncoding.EncodeToString(data)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    payload + "." + s.sign(payload),
+		Path:     "/",
+		MaxAge:   int(maxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   s.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Pop returns the queued message and clears the cookie so it renders once.
+// tampered and expired cookies are cleared and ignored
+func (s *Store) Pop(w http.ResponseWriter, r *http.Request) (Message, bool) {
+	cookie, err := r.Cookie(cookieName)
+	if err != nil {
+		return Message{}, false
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   s.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	payload, sig, ok := strings.Cut(cookie.Value, ".")
+	if !ok || !crypto.ConstantTimeEqual(sig, s.sign(payload)) {
+		return Message{}, false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return Message{}, false
+	}
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil || time.Now().Unix() > msg.Expires {
+		return Message{}, false
+	}
+	return msg, true
+}
+
+func (s *Store) sign(payload string) string {
+	return s.signer.Hash(signaturePurpose + payload)
+}
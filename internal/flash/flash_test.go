@@ -0,0 +1,93 @@
+package flash
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shelterkin/shelterkin/components"
+	"github.com/shelterkin/shelterkin/internal/testutil"
+)
+
+// renderNext mimics the page after a redirect: it pops the flash and renders it
+func renderNext(store *Store, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/", nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	if msg, ok := store.Pop(rec, req); ok {
+		components.Flash(msg.Level, msg.Message).Render(req.Context(), rec)
+	}
+	return rec
+}
+
+func TestFlashShownOnceThenCleared(t *testing.T) {
+	store := NewStore(testutil.NewTestHMAC(t), true)
+
+	redirect := httptest.NewRecorder()
+	store.Set(redirect, "success", "Password changed <script>alert(1)</script>")
+
+	first := renderNext(store, redirect.Result().Cookies())
+	body := first.Body.String()
+	if !strings.Contains(body, "alert-success") || !strings.Contains(body, "Password changed") {
+		t.Fatalf("expected the flash on the next page, got %s", body)
+	}
+	if strings.Contains(body, "<script>") {
+		t.Errorf("expected message to be escaped, got %s", body)
+	}
+
+	cleared := first.Result().Cookies()
+	if len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Fatalf("expected the flash cookie to be cleared, got %+v", cleared)
+	}
+
+	// the browser drops the cleared cookie, so the page after shows nothing
+	if second := renderNext(store, nil); second.Body.Len() != 0 {
+		t.Errorf("expected no flash on the following page, got %s", second.Body.String())
+	}
+}
+
+func TestFlashRejectsTamperedCookie(t *testing.T) {
+	store := NewStore(testutil.NewTestHMAC(t), true)
+
+	redirect := httptest.NewRecorder()
+	store.Set(redirect, "success", "Saved")
+	cookie := redirect.Result().Cookies()[0]
+	cookie.Value = "eyJsZXZlbCI6ImVycm9yIn0" + cookie.Value[strings.Index(cookie.Value, "."):]
+
+	if rec := renderNext(store, []*http.Cookie{cookie}); rec.Body.Len() != 0 {
+		t.Errorf("expected tampered flash to be ignored, got %s", rec.Body.String())
+	}
+}
+
+func TestFlashCookieSecureFollowsDeployment(t *testing.T) {
+	for _, secure := range []bool{true, false} {
+		store := NewStore(testutil.NewTestHMAC(t), secure)
+
+		redirect := httptest.NewRecorder()
+		store.Set(redirect, "success", "Saved")
+		cookies := redirect.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Secure != secure {
+			t.Fatalf("expected Secure=%v, got %+v", secure, cookies)
+		}
+
+		// still shown over plain http
+		if rec := renderNext(store, cookies); !strings.Contains(rec.Body.String(), "Saved") {
+			t.Errorf("secure=%v: expected the flash to render, got %s", secure, rec.Body.String())
+		}
+	}
+}
+
+func TestFlashSignatureIsNotALookupHash(t *testing.T) {
+	signer := testutil.NewTestHMAC(t)
+	store := NewStore(signer, true)
+
+	redirect := httptest.NewRecorder()
+	store.Set(redirect, "success", "Saved")
+	payload, sig, _ := strings.Cut(redirect.Result().Cookies()[0].Value, ".")
+	if sig == signer.Hash(payload) {
+		t.Error("expected the flash signature to differ from the plain lookup hash of its payload")
+	}
+}
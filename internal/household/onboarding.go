@@ -0,0 +1,85 @@
+package household
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+)
+
+const (
+	StepProfile       = "profile"
+	StepHousehold     = "household"
+	StepCareRecipient = "care_recipient"
+	StepInvite        = "invite"
+	StepCompleted     = "completed"
+)
+
+// onboardingSteps is the only order a new household moves through
+var onboardingSteps = []string{StepProfile, StepHousehold, StepCareRecipient, StepInvite, StepCompleted}
+
+type Onboarding struct {
+	Step string `json:"step"`
+}
+
+func (o Onboarding) Completed() bool {
+	return o.Step == StepCompleted
+}
+
+// GetOnboarding restarts from the first step when the stored progress is
+// malformed or names a step that no longer exists
+func (s *Service) GetOnboarding(ctx context.Context, householdID string) (Onboarding, error) {
+	household, err := s.queries.GetHouseholdByID(ctx, householdID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Onboarding{}, apperror.NotFound("household", householdID)
+	}
+	if err != nil {
+		return Onboarding{}, apperror.Internal("loading household", err)
+	}
+
+	var progress Onboarding
+	if err := json.Unmarshal([]byte(household.OnboardingProgress), &progress); err != nil || !slices.Contains(onboardingSteps, progress.Step) {
+		slog.Warn("onboarding progress is invalid, restarting", "household_id", householdID, "progress", household.OnboardingProgress)
+		return Onboarding{Step: onboardingSteps[0]}, nil
+	}
+	return progress, nil
+}
+
+// AdvanceOnboarding moves the household to step, which must be the current
+// step or the one right after it; repeating the current step is a no-op
+func (s *Service) AdvanceOnboarding(ctx context.Context, householdID, step string) (Onboarding, error) {
+	current, err := s.GetOnboarding(ctx, householdID)
+	if err != nil {
+		return Onboarding{}, err
+	}
+
+	target := slices.Index(onboardingSteps, step)
+	if target == -1 {
+		return Onboarding{}, apperror.Validation("step", "Unknown onboarding step")
+	}
+	position := slices.Index(onboardingSteps, current.Step)
+	if target == position {
+		return current, nil
+	}
+	if target != position+1 {
+		return Onboarding{}, apperror.Validation("step", "Onboarding steps must be completed in order")
+	}
+
+	next := Onboarding{Step: step}
+	data, err := json.Marshal(next)
+	if err != nil {
+		return Onboarding{}, apperror.Internal("encoding onboarding progress", err)
+	}
+	if err := s.queries.UpdateHouseholdOnboarding(ctx, dbgen.UpdateHouseholdOnboardingParams{
+		OnboardingProgress: string(data),
+		ID:                 householdID,
+	}); err != nil {
+		return Onboarding{}, apperror.Internal("saving onboarding progress", err)
+	}
+	return next, nil
+}
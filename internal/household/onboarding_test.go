@@ -0,0 +1,77 @@
+package household
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+	"github.com/shelterkin/shelterkin/internal/testutil"
+)
+
+func TestAdvanceOnboardingInOrder(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	createHousehold(t, dbgen.New(sqlDB), "h1", "{}")
+	svc := NewService(sqlDB)
+	ctx := context.Background()
+
+	got, err := svc.AdvanceOnboarding(ctx, "h1", StepHousehold)
+	if err != nil {
+		t.Fatalf("advancing: %v", err)
+	}
+	if got.Step != StepHousehold {
+		t.Errorf("expected %q, got %q", StepHousehold, got.Step)
+	}
+
+	stored, err := svc.GetOnboarding(ctx, "h1")
+	if err != nil {
+		t.Fatalf("getting: %v", err)
+	}
+	if stored.Step != StepHousehold {
+		t.Errorf("expected stored step %q, got %q", StepHousehold, stored.Step)
+	}
+}
+
+func TestAdvanceOnboardingRejectsOutOfOrder(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	createHousehold(t, dbgen.New(sqlDB), "h1", "{}")
+	svc := NewService(sqlDB)
+	ctx := context.Background()
+
+	for _, step := range []string{StepInvite, StepCompleted, "bogus"} {
+		_, err := svc.AdvanceOnboarding(ctx, "h1", step)
+		var appErr *apperror.Error
+		if !errors.As(err, &appErr) || appErr.Type != apperror.TypeValidation {
+			t.Errorf("%s: expected validation error, got %v", step, err)
+		}
+	}
+
+	if _, err := svc.AdvanceOnboarding(ctx, "h1", StepHousehold); err != nil {
+		t.Fatalf("advancing: %v", err)
+	}
+	if _, err := svc.AdvanceOnboarding(ctx, "h1", StepProfile); err == nil {
+		t.Error("expected regressing to an earlier step to fail")
+	}
+}
+
+func TestAdvanceOnboardingCompletionIsIdempotent(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	createHousehold(t, dbgen.New(sqlDB), "h1", "{}")
+	svc := NewService(sqlDB)
+	ctx := context.Background()
+
+	for _, step := range onboardingSteps[1:] {
+		if _, err := svc.AdvanceOnboarding(ctx, "h1", step); err != nil {
+			t.Fatalf("advancing to %s: %v", step, err)
+		}
+	}
+
+	got, err := svc.AdvanceOnboarding(ctx, "h1", StepCompleted)
+	if err != nil {
+		t.Fatalf("repeating completion: %v", err)
+	}
+	if !got.Completed() {
+		t.Errorf("expected completed, got %q", got.Step)
+	}
+}
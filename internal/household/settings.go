@@ -0,0 +1,105 @@
+package household
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+)
+
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+
+type Service struct {
+	queries *dbgen.Queries
+}
+
+func NewService(db *sql.DB) *Service {
+	return &Service{queries: dbgen.New(db)}
+}
+
+type Settings struct {
+	Timezone      string               `json:"timezone"`
+	Locale        string               `json:"locale"`
+	Notifications NotificationSettings `json:"notifications"`
+}
+
+type NotificationSettings struct {
+	Email       bool `json:"email"`
+	DailyDigest bool `json:"daily_digest"`
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		Timezone:      "America/New_York",
+		Locale:        "en-US",
+		Notifications: NotificationSettings{Email: true},
+	}
+}
+
+// DecodeSettings parses submitted settings, rejecting fields the struct does
+// not know about so typos are not silently dropped
+func DecodeSettings(r io.Reader) (Settings, error) {
+	settings := DefaultSettings()
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&settings); err != nil {
+		return Settings{}, apperror.Validation("settings", "Settings contain an unknown or malformed field")
+	}
+	return settings, nil
+}
+
+// GetSettings falls back to defaults when the stored blob is malformed so a
+// bad write can never lock a household out of its settings page
+func (s *Service) GetSettings(ctx context.Context, householdID string) (Settings, error) {
+	household, err := s.queries.GetHouseholdByID(ctx, householdID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Settings{}, apperror.NotFound("household", householdID)
+	}
+	if err != nil {
+		return Settings{}, apperror.Internal("loading household", err)
+	}
+
+	settings := DefaultSettings()
+	if err := json.Unmarshal([]byte(household.Settings), &settings); err != nil {
+		slog.Warn("household settings are malformed, using defaults", "household_id", householdID, "error", err)
+		return DefaultSettings(), nil
+	}
+	return settings, nil
+}
+
+func (s *Service) UpdateSettings(ctx context.Context, householdID string, settings Settings) error {
+	var errs apperror.ValidationErrors
+	if _, err := time.LoadLocation(settings.Timezone); err != nil || settings.Timezone == "" {
+		errs.Add("timezone", "Choose a valid timezone")
+	}
+	if !localePattern.MatchString(settings.Locale) {
+		errs.Add("locale", "Choose a valid locale")
+	}
+	if err := errs.ToError(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return apperror.Internal("encoding settings", err)
+	}
+
+	rows, err := s.queries.UpdateHouseholdSettings(ctx, dbgen.UpdateHouseholdSettingsParams{
+		Settings: string(data),
+		ID:       householdID,
+	})
+	if err != nil {
+		return apperror.Internal("saving settings", err)
+	}
+	if rows == 0 {
+		return apperror.NotFound("household", householdID)
+	}
+	return nil
+}
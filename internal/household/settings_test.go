@@ -0,0 +1,127 @@
+package household
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+	"github.com/shelterkin/shelterkin/internal/testutil"
+)
+
+func createHousehold(t *testing.T, queries *dbgen.Queries, id, settings string) {
+	t.Helper()
+	if _, err := queries.CreateHousehold(context.Background(), dbgen.CreateHouseholdParams{
+		ID: id, NameEnc: "enc", EncryptionSalt: "salt", OnboardingProgress: `{"step":"profile"}`, Settings: settings,
+	}); err != nil {
+		t.Fatalf("creating household: %v", err)
+	}
+}
+
+func TestSettingsRoundTrip(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	createHousehold(t, dbgen.New(sqlDB), "h1", "{}")
+	svc := NewService(sqlDB)
+	ctx := context.Background()
+
+	got, err := svc.GetSettings(ctx, "h1")
+	if err != nil {
+		t.Fatalf("getting defaults: %v", err)
+	}
+	if got != DefaultSettings() {
+		t.Errorf("expected defaults for empty settings, got %+v", got)
+	}
+
+	want := Settings{
+		Timezone:      "Europe/London",
+		Locale:        "en-GB",
+		Notifications: NotificationSettings{Email: false, DailyDigest: true},
+	}
+	if err := svc.UpdateSettings(ctx, "h1", want); err != nil {
+		t.Fatalf("updating: %v", err)
+	}
+
+	got, err = svc.GetSettings(ctx, "h1")
+	if err != nil {
+		t.Fatalf("getting: %v", err)
+	}
+	if got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestSettingsIsolatedPerHousehold(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	queries := dbgen.New(sqlDB)
+	createHousehold(t, queries, "h1", "{}")
+	createHousehold(t, queries, "h2", "{}")
+	svc := NewService(sqlDB)
+	ctx := context.Background()
+
+	changed := DefaultSettings()
+	changed.Timezone = "Asia/Tokyo"
+	if err := svc.UpdateSettings(ctx, "h1", changed); err != nil {
+		t.Fatalf("updating: %v", err)
+	}
+
+	got, err := svc.GetSettings(ctx, "h2")
+	if err != nil {
+		t.Fatalf("getting: %v", err)
+	}
+	if got.Timezone != DefaultSettings().Timezone {
+		t.Errorf("expected h2 to keep default timezone, got %q", got.Timezone)
+	}
+}
+
+func TestGetSettingsMalformedFallsBackToDefaults(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	createHousehold(t, dbgen.New(sqlDB), "h1", "{not json")
+
+	got, err := NewService(sqlDB).GetSettings(context.Background(), "h1")
+	if err != nil {
+		t.Fatalf("expected no error for malformed settings, got %v", err)
+	}
+	if got != DefaultSettings() {
+		t.Errorf("expected defaults, got %+v", got)
+	}
+}
+
+func TestUpdateSettingsValidation(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	createHousehold(t, dbgen.New(sqlDB), "h1", "{}")
+
+	err := NewService(sqlDB).UpdateSettings(context.Background(), "h1", Settings{Timezone: "Mars/Olympus", Locale: "english"})
+
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Type != apperror.TypeValidation {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	if len(appErr.Fields) != 2 {
+		t.Errorf("expected timezone and locale errors, got %+v", appErr.Fields)
+	}
+}
+
+func TestUpdateSettingsUnknownHousehold(t *testing.T) {
+	err := NewService(testutil.NewTestDB(t)).UpdateSettings(context.Background(), "missing", DefaultSettings())
+
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Type != apperror.TypeNotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestDecodeSettingsRejectsUnknownFields(t *testing.T) {
+	if _, err := DecodeSettings(strings.NewReader(`{"timezone":"UTC","colour":"blue"}`)); err == nil {
+		t.Error("expected unknown field to be rejected")
+	}
+
+	got, err := DecodeSettings(strings.NewReader(`{"timezone":"UTC"}`))
+	if err != nil {
+		t.Fatalf("decoding: %v", err)
+	}
+	if got.Timezone != "UTC" || got.Locale != DefaultSettings().Locale {
+		t.Errorf("expected timezone override on top of defaults, got %+v", got)
+	}
+}
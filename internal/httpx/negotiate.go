@@ -0,0 +1,69 @@
+// Package httpx holds small request helpers shared by handlers and middleware
+package httpx
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Mode is how a response should be rendered for the client that asked
+type Mode int
+
+const (
+	// HTML is a full page, the default for browsers and anything unknown
+	HTML Mode = iota
+	// HTMX is a fragment swapped into an existing page
+	HTMX
+	// JSON is for api clients that asked for it explicitly
+	JSON
+)
+
+func (m Mode) String() string {
+	switch m {
+	case HTMX:
+		return "htmx"
+	case JSON:
+		return "json"
+	default:
+		return "html"
+	}
+}
+
+// Negotiate picks the rendering mode. HX-Request wins outright; otherwise
+// JSON needs an Accept that ranks application/json above text/html. wildcards
+// don't count against it, so "application/json, */*" is JSON while a
+// browser's "text/html,...,*/*;q=0.8" stays HTML
+func Negotiate(r *http.Request) Mode {
+	if r.Header.Get("HX-Request") == "true" {
+		return HTMX
+	}
+
+	var jsonQ, htmlQ float64
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if v, ok := params["q"]; ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+			switch mediaType {
+			case "application/json":
+				jsonQ = max(jsonQ, q)
+			case "text/html":
+				htmlQ = max(htmlQ, q)
+			}
+		}
+	}
+
+	if jsonQ > 0 && jsonQ > htmlQ {
+		return JSON
+	}
+	return HTML
+}
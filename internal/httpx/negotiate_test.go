@@ -0,0 +1,41 @@
+package httpx
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name    string
+		htmx    string
+		accepts []string
+		want    Mode
+	}{
+		{"no headers", "", nil, HTML},
+		{"browser navigation", "", []string{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"}, HTML},
+		{"htmx request", "true", []string{"*/*"}, HTMX},
+		{"htmx wins over json accept", "true", []string{"application/json"}, HTMX},
+		{"htmx header not true", "false", nil, HTML},
+		{"json only", "", []string{"application/json"}, JSON},
+		{"json with wildcard fallback", "", []string{"application/json, text/plain, */*"}, JSON},
+		{"json preferred by q", "", []string{"text/html;q=0.5, application/json"}, JSON},
+		{"html preferred by q", "", []string{"application/json;q=0.5, text/html"}, HTML},
+		{"tie goes to html", "", []string{"application/json, text/html"}, HTML},
+		{"json refused", "", []string{"application/json;q=0"}, HTML},
+		{"split accept headers", "", []string{"text/html;q=0.1", "application/json"}, JSON},
+		{"malformed accept", "", []string{";;;"}, HTML},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if tt.htmx != "" {
+			r.Header.Set("HX-Request", tt.htmx)
+		}
+		for _, accept := range tt.accepts {
+			r.Header.Add("Accept", accept)
+		}
+		if got := Negotiate(r); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
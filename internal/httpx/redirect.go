@@ -0,0 +1,27 @@
+package httpx
+
+import (
+	"net/url"
+	"strings"
+)
+
+// SafeRedirect returns next when it is a path on this site, and "/"
+// otherwise, so a ?next= parameter can't send someone to another site after
+// they sign in. browsers treat a backslash like a slash and drop tabs and
+// newlines, so "/\evil.com" and "/\t/evil.com" are as dangerous as
+// "//evil.com" and are refused too
+func SafeRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.Contains(next, `\`) {
+		return "/"
+	}
+	for _, r := range next {
+		if r < 0x20 || r == 0x7f {
+			return "/"
+		}
+	}
+	u, err := url.Parse(next)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.User != nil {
+		return "/"
+	}
+	return next
+}
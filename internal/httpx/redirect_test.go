@@ -0,0 +1,26 @@
+package httpx
+
+import "testing"
+
+func TestSafeRedirect(t *testing.T) {
+	tests := []struct {
+		next string
+		want string
+	}{
+		{"/dashboard", "/dashboard"},
+		{"/medications/abc?tab=history#doses", "/medications/abc?tab=history#doses"},
+		{"", "/"},
+		{"dashboard", "/"},
+		{"//evil.com", "/"},
+		{"https://evil.com", "/"},
+		{"javascript:alert(1)", "/"},
+		{`/\evil.com`, "/"},
+		{"/\t/evil.com", "/"},
+		{"/\n/evil.com", "/"},
+	}
+	for _, tt := range tests {
+		if got := SafeRedirect(tt.next); got != tt.want {
+			t.Errorf("SafeRedirect(%q) = %q, want %q", tt.next, got, tt.want)
+		}
+	}
+}
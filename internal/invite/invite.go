@@ -0,0 +1,183 @@
+package invite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/crypto"
+	"github.com/shelterkin/shelterkin/internal/database"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+	"github.com/shelterkin/shelterkin/internal/emailaddr"
+	"github.com/shelterkin/shelterkin/internal/role"
+	"github.com/shelterkin/shelterkin/internal/ulid"
+)
+
+const (
+	MaxTTL  = 30 * 24 * time.Hour
+	MaxUses = 25
+)
+
+var assignableRoles = []string{role.Admin, role.Member, role.Caregiver, role.ReadOnly}
+
+type Service struct {
+	queries *dbgen.Queries
+	hmac    *crypto.HMACHasher
+}
+
+func NewService(db *sql.DB, hmac *crypto.HMACHasher) *Service {
+	return &Service{queries: dbgen.New(db), hmac: hmac}
+}
+
+type CreateInviteInput struct {
+	Role string
+	TTL  time.Duration
+	// Email locks the invite to one address when set
+	Email string
+	// MaxUses defaults to a single-use invite when zero
+	MaxUses int
+}
+
+// CreateInvite stores only the token's hmac; the plaintext token is returned
+// once so the caller can build the link and is never recoverable afterwards
+func (s *Service) CreateInvite(ctx context.Context, actingUser dbgen.User, input CreateInviteInput) (string, dbgen.Invite, error) {
+	if !role.AtLeast(actingUser.Role, role.Admin) {
+		return "", dbgen.Invite{}, apperror.Forbidden("Only admins can invite members")
+	}
+
+	var errs apperror.ValidationErrors
+	if !slices.Contains(assignableRoles, input.Role) {
+		errs.Add("role", "Choose a valid role")
+	}
+	if input.TTL <= 0 || input.TTL > MaxTTL {
+		errs.Add("ttl", "Invites can last at most 30 days")
+	}
+	maxUses := input.MaxUses
+	if maxUses == 0 {
+		maxUses = 1
+	}
+	if maxUses < 1 || maxUses > MaxUses {
+		errs.Add("max_uses", fmt.Sprintf("An invite can be used at most %d times", MaxUses))
+	}
+	email := emailaddr.Normalize(input.Email)
+	if problem := emailaddr.Problem(email); email != "" && problem != "" {
+		errs.Add("email", problem)
+	}
+	if err := errs.ToError(); err != nil {
+		return "", dbgen.Invite{}, err
+	}
+
+	token, err := crypto.GenerateToken()
+	if err != nil {
+		return "", dbgen.Invite{}, apperror.Internal("generating invite token", err)
+	}
+
+	var emailHash sql.NullString
+	if email != "" {
+		emailHash = sql.NullString{String: s.hmac.Hash(email), Valid: true}
+	}
+
+	inv, err := s.queries.CreateInvite(ctx, dbgen.CreateInviteParams{
+		ID:          ulid.New(),
+		HouseholdID: actingUser.HouseholdID,
+		InvitedBy:   actingUser.ID,
+		EmailHash:   emailHash,
+		TokenHash:   s.hmac.Hash(token),
+		Role:        input.Role,
+		ExpiresAt:   time.Now().UTC().Add(input.TTL).Format(database.TimestampFormat),
+		MaxUses:     int64(maxUses),
+	})
+	if err != nil {
+		return "", dbgen.Invite{}, apperror.Internal("creating invite", err)
+	}
+	return token, inv, nil
+}
+
+func (s *Service) ListInvites(ctx context.Context, householdID string) ([]dbgen.Invite, error) {
+	invites, err := s.queries.ListPendingInvitesByHousehold(ctx, householdID)
+	if err != nil {
+		return nil, apperror.Internal("listing invites", err)
+	}
+	return invites, nil
+}
+
+func (s *Service) RevokeInvite(ctx context.Context, householdID, inviteID string) error {
+	rows, err := s.queries.RevokeInvite(ctx, dbgen.RevokeInviteParams{ID: inviteID, HouseholdID: householdID})
+	if err != nil {
+		return apperror.Internal("revoking invite", err)
+	}
+	if rows == 0 {
+		return apperror.NotFound("invite", inviteID)
+	}
+	return nil
+}
+
+// Accept uses up one registration on a pending invite for the given email.
+// expired, revoked and already used tokens all get the same message so a
+// link reveals nothing
+func (s *Service) Accept(ctx context.Context, token, email string) (dbgen.Invite, error) {
+	invalid := apperror.Validation("invite", "This invite link is invalid or has expired")
+
+	inv, err := s.queries.GetInviteByToken(ctx, s.hmac.Hash(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return dbgen.Invite{}, invalid
+	}
+	if err != nil {
+		return dbgen.Invite{}, apperror.Internal("loading invite", err)
+	}
+	if inv.EmailHash.Valid && !crypto.ConstantTimeEqual(s.hmac.Hash(emailaddr.Normalize(email)), inv.EmailHash.String) {
+		return dbgen.Invite{}, apperror.Validation("email", "This invite was sent to a different email address")
+	}
+	if err := s.checkGrantable(ctx, inv); err != nil {
+		return dbgen.Invite{}, err
+	}
+
+	rows, err := s.queries.AcceptInvite(ctx, inv.ID)
+	if err != nil {
+		return dbgen.Invite{}, apperror.Internal("accepting invite", err)
+	}
+	// other registrations used it up or it was revoked between the two queries
+	if rows == 0 {
+		return dbgen.Invite{}, invalid
+	}
+	return inv, nil
+}
+
+// checkGrantable re-checks the invite's role against the inviter as they are
+// now, so a row written around CreateInvite, or an inviter since demoted or
+// removed, can't hand out more access than the inviter holds
+func (s *Service) checkGrantable(ctx context.Context, inv dbgen.Invite) error {
+	invalid := apperror.Validation("invite", "This invite link is invalid or has expired")
+	if !slices.Contains(assignableRoles, inv.Role) {
+		return invalid
+	}
+
+	inviter, err := s.queries.GetUserByID(ctx, dbgen.GetUserByIDParams{ID: inv.InvitedBy, HouseholdID: inv.HouseholdID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return invalid
+	}
+	if err != nil {
+		return apperror.Internal("loading inviter", err)
+	}
+	if !role.AtLeast(inviter.Role, inv.Role) {
+		return invalid
+	}
+	return nil
+}
+
+// Link builds the shareable registration url for a freshly created token.
+// baseURL may carry a path prefix and a trailing slash
+func Link(baseURL, token string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid base url %q", baseURL)
+	}
+	u = u.JoinPath("register")
+	u.RawQuery = url.Values{"token": {token}}.Encode()
+	return u.String(), nil
+}
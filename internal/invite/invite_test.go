@@ -0,0 +1,304 @@
+package invite
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+	"github.com/shelterkin/shelterkin/internal/emailaddr"
+	"github.com/shelterkin/shelterkin/internal/testutil"
+)
+
+func setup(t *testing.T) (*Service, dbgen.User) {
+	t.Helper()
+	sqlDB := testutil.NewTestDB(t)
+	queries := dbgen.New(sqlDB)
+	ctx := context.Background()
+
+	if _, err := queries.CreateHousehold(ctx, dbgen.CreateHouseholdParams{
+		ID: "h1", NameEnc: "enc", EncryptionSalt: "salt", OnboardingProgress: "{}", Settings: "{}",
+	}); err != nil {
+		t.Fatalf("creating household: %v", err)
+	}
+	admin, err := queries.CreateUser(ctx, dbgen.CreateUserParams{
+		ID: "u1", HouseholdID: "h1", EmailEnc: "enc", EmailHash: "hash", DisplayNameEnc: "enc",
+		Role: "admin", AuthProvider: "local", Timezone: "UTC",
+	})
+	if err != nil {
+		t.Fatalf("creating admin: %v", err)
+	}
+	return NewService(sqlDB, testutil.NewTestHMAC(t)), admin
+}
+
+func requireType(t *testing.T, err error, want apperror.Type) {
+	t.Helper()
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Type != want {
+		t.Fatalf("expected apperror type %d, got %v", want, err)
+	}
+}
+
+func TestCreateAndListInvites(t *testing.T) {
+	svc, admin := setup(t)
+	ctx := context.Background()
+
+	token, inv, err := svc.CreateInvite(ctx, admin, CreateInviteInput{Role: "member", TTL: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("creating invite: %v", err)
+	}
+	if token == "" || inv.TokenHash == token {
+		t.Error("expected a plaintext token distinct from the stored hash")
+	}
+
+	invites, err := svc.ListInvites(ctx, "h1")
+	if err != nil {
+		t.Fatalf("listing: %v", err)
+	}
+	if len(invites) != 1 || invites[0].ID != inv.ID {
+		t.Errorf("expected the new invite to be pending, got %+v", invites)
+	}
+}
+
+func TestCreateInviteValidation(t *testing.T) {
+	svc, admin := setup(t)
+	ctx := context.Background()
+
+	_, _, err := svc.CreateInvite(ctx, admin, CreateInviteInput{Role: "owner", TTL: MaxTTL + time.Hour})
+	requireType(t, err, apperror.TypeValidation)
+
+	member := admin
+	member.Role = "member"
+	_, _, err = svc.CreateInvite(ctx, member, CreateInviteInput{Role: "member", TTL: time.Hour})
+	requireType(t, err, apperror.TypeForbidden)
+}
+
+func TestCaregiverCannotCreateInvite(t *testing.T) {
+	svc, admin := setup(t)
+
+	// even for their own role, inviting is an admin task
+	caregiver := admin
+	caregiver.Role = "caregiver"
+	_, _, err := svc.CreateInvite(context.Background(), caregiver, CreateInviteInput{Role: "caregiver", TTL: time.Hour})
+	requireType(t, err, apperror.TypeForbidden)
+}
+
+func TestAcceptRejectsRoleAboveInviter(t *testing.T) {
+	sqlDB := testutil.NewTestDB(t)
+	household := testutil.CreateTestHousehold(t, sqlDB)
+	caregiver := testutil.CreateTestUser(t, sqlDB, household.ID, testutil.WithRole("caregiver"))
+	svc := NewService(sqlDB, testutil.NewTestHMAC(t))
+
+	// written straight to the table, the way a bug or a crafted request
+	// that skipped CreateInvite would leave it
+	inv, token := testutil.CreateTestInvite(t, sqlDB, household.ID, caregiver.ID, testutil.WithInviteRole("admin"))
+
+	_, err := svc.Accept(context.Background(), token, "new@example.com")
+	requireType(t, err, apperror.TypeValidation)
+
+	// the rejected attempt must not use up the invite
+	invites, err := svc.ListInvites(context.Background(), household.ID)
+	if err != nil {
+		t.Fatalf("listing: %v", err)
+	}
+	if len(invites) != 1 || invites[0].ID != inv.ID || invites[0].UseCount != 0 {
+		t.Errorf("expected the invite to stay unused, got %+v", invites)
+	}
+}
+
+func TestRevokedInviteCannotBeAccepted(t *testing.T) {
+	svc, admin := setup(t)
+	ctx := context.Background()
+
+	token, inv, err := svc.CreateInvite(ctx, admin, CreateInviteInput{Role: "member", TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("creating invite: %v", err)
+	}
+	if err := svc.RevokeInvite(ctx, "h1", inv.ID); err != nil {
+		t.Fatalf("revoking: %v", err)
+	}
+
+	_, err = svc.Accept(ctx, token, "new@example.com")
+	requireType(t, err, apperror.TypeValidation)
+
+	invites, _ := svc.ListInvites(ctx, "h1")
+	if len(invites) != 0 {
+		t.Errorf("expected revoked invite to leave the pending list, got %d", len(invites))
+	}
+}
+
+func TestRevokeInviteScopedToHousehold(t *testing.T) {
+	svc, admin := setup(t)
+	ctx := context.Background()
+
+	_, inv, err := svc.CreateInvite(ctx, admin, CreateInviteInput{Role: "member", TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("creating invite: %v", err)
+	}
+
+	requireType(t, svc.RevokeInvite(ctx, "other-household", inv.ID), apperror.TypeNotFound)
+}
+
+func TestAcceptConsumesInvite(t *testing.T) {
+	svc, admin := setup(t)
+	ctx := context.Background()
+
+	token, _, err := svc.CreateInvite(ctx, admin, CreateInviteInput{Role: "member", TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("creating invite: %v", err)
+	}
+
+	inv, err := svc.Accept(ctx, token, "new@example.com")
+	if err != nil {
+		t.Fatalf("accepting: %v", err)
+	}
+	if inv.Role != "member" || inv.HouseholdID != "h1" {
+		t.Errorf("unexpected invite %+v", inv)
+	}
+
+	_, err = svc.Accept(ctx, token, "new@example.com")
+	requireType(t, err, apperror.TypeValidation)
+}
+
+func TestCaregiverInvite(t *testing.T) {
+	svc, admin := setup(t)
+	ctx := context.Background()
+
+	for _, r := range []string{"caregiver", "readonly"} {
+		token, _, err := svc.CreateInvite(ctx, admin, CreateInviteInput{Role: r, TTL: time.Hour})
+		if err != nil {
+			t.Fatalf("creating %s invite: %v", r, err)
+		}
+		inv, err := svc.Accept(ctx, token, r+"@example.com")
+		if err != nil {
+			t.Fatalf("accepting %s invite: %v", r, err)
+		}
+		if inv.Role != r {
+			t.Errorf("expected a %s invite, got %q", r, inv.Role)
+		}
+	}
+}
+
+func TestAcceptLooksUpByHashOnly(t *testing.T) {
+	svc, admin := setup(t)
+	ctx := context.Background()
+
+	token, inv, err := svc.CreateInvite(ctx, admin, CreateInviteInput{Role: "member", TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("creating invite: %v", err)
+	}
+
+	// someone who read the invites table holds only the hash, which must not
+	// work as a link
+	_, err = svc.Accept(ctx, inv.TokenHash, "new@example.com")
+	requireType(t, err, apperror.TypeValidation)
+
+	if _, err := svc.Accept(ctx, token, "new@example.com"); err != nil {
+		t.Fatalf("expected the plaintext token to be accepted, got %v", err)
+	}
+}
+
+func TestEmailLockedInvite(t *testing.T) {
+	svc, admin := setup(t)
+	ctx := context.Background()
+
+	token, _, err := svc.CreateInvite(ctx, admin, CreateInviteInput{Role: "member", TTL: time.Hour, Email: "Carer@Example.com"})
+	if err != nil {
+		t.Fatalf("creating invite: %v", err)
+	}
+
+	_, err = svc.Accept(ctx, token, "someone-else@example.com")
+	requireType(t, err, apperror.TypeValidation)
+
+	if _, err := svc.Accept(ctx, token, " carer@example.com"); err != nil {
+		t.Fatalf("expected the invited email to be accepted, got %v", err)
+	}
+}
+
+func TestEmailLockIgnoresUnicodeComposition(t *testing.T) {
+	svc, admin := setup(t)
+	ctx := context.Background()
+
+	// "é" as e + combining acute accent, then precomposed
+	token, _, err := svc.CreateInvite(ctx, admin, CreateInviteInput{Role: "member", TTL: time.Hour, Email: "rene\u0301@example.com"})
+	if err != nil {
+		t.Fatalf("creating invite: %v", err)
+	}
+	if _, err := svc.Accept(ctx, token, "ren\u00e9@example.com"); err != nil {
+		t.Fatalf("expected composed and decomposed forms to match, got %v", err)
+	}
+}
+
+func TestCreateInviteRejectsOverlongEmail(t *testing.T) {
+	svc, admin := setup(t)
+
+	email := strings.Repeat("a", emailaddr.MaxLength) + "@example.com"
+	_, _, err := svc.CreateInvite(context.Background(), admin, CreateInviteInput{Role: "member", TTL: time.Hour, Email: email})
+	requireType(t, err, apperror.TypeValidation)
+}
+
+func TestMultiUseInviteCap(t *testing.T) {
+	svc, admin := setup(t)
+	ctx := context.Background()
+
+	token, _, err := svc.CreateInvite(ctx, admin, CreateInviteInput{Role: "member", TTL: time.Hour, MaxUses: 3})
+	if err != nil {
+		t.Fatalf("creating invite: %v", err)
+	}
+
+	for i := range 3 {
+		if _, err := svc.Accept(ctx, token, "carer@example.com"); err != nil {
+			t.Fatalf("registration %d: %v", i+1, err)
+		}
+		pending, _ := svc.ListInvites(ctx, "h1")
+		if wantPending := i < 2; (len(pending) == 1) != wantPending {
+			t.Errorf("after %d uses expected pending=%v, got %d pending", i+1, wantPending, len(pending))
+		}
+	}
+
+	_, err = svc.Accept(ctx, token, "carer@example.com")
+	requireType(t, err, apperror.TypeValidation)
+}
+
+func TestLinkRoundTripsToken(t *testing.T) {
+	svc, admin := setup(t)
+	ctx := context.Background()
+
+	token, _, err := svc.CreateInvite(ctx, admin, CreateInviteInput{Role: "member", TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("creating invite: %v", err)
+	}
+
+	for _, base := range []string{"https://care.example.com", "https://care.example.com/", "https://example.com/shelterkin/"} {
+		link, err := Link(base, token)
+		if err != nil {
+			t.Fatalf("building link from %q: %v", base, err)
+		}
+		u, err := url.Parse(link)
+		if err != nil {
+			t.Fatalf("parsing %q: %v", link, err)
+		}
+		if !strings.HasSuffix(u.Path, "/register") || strings.Contains(u.Path, "//") {
+			t.Errorf("expected a single /register path segment, got %q", link)
+		}
+		if u.Query().Get("token") != token {
+			t.Errorf("expected token to survive the round trip, got %q", link)
+		}
+	}
+
+	link, _ := Link("https://care.example.com", token)
+	u, _ := url.Parse(link)
+	if _, err := svc.Accept(ctx, u.Query().Get("token"), "new@example.com"); err != nil {
+		t.Fatalf("expected the link's token to be accepted, got %v", err)
+	}
+}
+
+func TestLinkRejectsRelativeBaseURL(t *testing.T) {
+	if _, err := Link("care.example.com", "token"); err == nil {
+		t.Error("expected error for a base url without a scheme")
+	}
+}
@@ -0,0 +1,102 @@
+// Package loginattempt records sign-in attempts for rate limiting, optionally
+// keeping only the network an attempt came from rather than the exact address
+package loginattempt
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+	"github.com/shelterkin/shelterkin/internal/ulid"
+)
+
+type Recorder struct {
+	queries *dbgen.Queries
+	coarsen bool
+}
+
+// NewRecorder stores coarsened addresses when coarsen is set. both writes and
+// lookups go through the same coarsening, so the ip limiter keeps working,
+// just per network instead of per address
+func NewRecorder(db *sql.DB, coarsen bool) *Recorder {
+	return &Recorder{queries: dbgen.New(db), coarsen: coarsen}
+}
+
+func (r *Recorder) Record(ctx context.Context, emailHash, ip string, succeeded bool) error {
+	var ok int64
+	if succeeded {
+		ok = 1
+	}
+	err := r.queries.CreateLoginAttempt(ctx, dbgen.CreateLoginAttemptParams{
+		ID:        ulid.New(),
+		EmailHash: emailHash,
+		IpAddress: r.address(ip),
+		Succeeded: ok,
+	})
+	if err != nil {
+		return fmt.Errorf("recording login attempt: %w", err)
+	}
+	return nil
+}
+
+// FailuresByIP counts failed attempts from ip within window
+func (r *Recorder) FailuresByIP(ctx context.Context, ip string, window time.Duration) (int64, error) {
+	count, err := r.queries.CountRecentFailedByIP(ctx, dbgen.CountRecentFailedByIPParams{
+		IpAddress: r.address(ip),
+		Datetime:  fmt.Sprintf("-%d seconds", int64(window.Seconds())),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("counting failed logins by ip: %w", err)
+	}
+	return count, nil
+}
+
+const (
+	baseBackoff = time.Second
+	// MaxBackoff caps the wait however many failures pile up, reached from
+	// the eleventh failure on
+	MaxBackoff = 15 * time.Minute
+)
+
+// Backoff is the Retry-After for a sign-in with this many recent failures.
+// it doubles per failure from one second, so one typo costs a moment while
+// a guessing script quickly hits MaxBackoff
+func Backoff(failures int64) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	// past this shift the wait is beyond the cap anyway
+	if failures > 20 {
+		return MaxBackoff
+	}
+	return min(baseBackoff<<(failures-1), MaxBackoff)
+}
+
+func (r *Recorder) address(ip string) string {
+	if !r.coarsen {
+		return ip
+	}
+	return CoarsenIP(ip)
+}
+
+// CoarsenIP reduces an address to its /24 for ipv4 or /48 for ipv6, roughly
+// a site rather than a device. anything unparseable is returned unchanged
+func CoarsenIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap()
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ip
+	}
+	return prefix.String()
+}
@@ -0,0 +1,99 @@
+package loginattempt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/testutil"
+)
+
+func TestCoarsenIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"203.0.113.57", "203.0.113.0/24"},
+		{"::ffff:203.0.113.57", "203.0.113.0/24"},
+		{"2001:db8:abcd:12::1", "2001:db8:abcd::/48"},
+		{"not-an-ip", "not-an-ip"},
+	}
+	for _, tt := range tests {
+		if got := CoarsenIP(tt.ip); got != tt.want {
+			t.Errorf("CoarsenIP(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestCoarsenedRecorderStillLimitsByNetwork(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	r := NewRecorder(db, true)
+	ctx := context.Background()
+
+	for _, ip := range []string{"203.0.113.5", "203.0.113.6", "203.0.113.7"} {
+		if err := r.Record(ctx, "hash", ip, false); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	if err := r.Record(ctx, "hash", "198.51.100.1", false); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	var exact int
+	if err := db.QueryRow("SELECT COUNT(*) FROM login_attempts WHERE ip_address NOT LIKE '%/%'").Scan(&exact); err != nil {
+		t.Fatalf("reading stored ips: %v", err)
+	}
+	if exact != 0 {
+		t.Errorf("expected only networks to be stored, found %d exact addresses", exact)
+	}
+
+	count, err := r.FailuresByIP(ctx, "203.0.113.200", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("FailuresByIP: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 failures from the network, got %d", count)
+	}
+}
+
+func TestRecorderKeepsExactAddressByDefault(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	r := NewRecorder(db, false)
+	ctx := context.Background()
+
+	if err := r.Record(ctx, "hash", "203.0.113.5", false); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := r.Record(ctx, "hash", "203.0.113.5", true); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	for ip, want := range map[string]int64{"203.0.113.5": 1, "203.0.113.6": 0} {
+		count, err := r.FailuresByIP(ctx, ip, 15*time.Minute)
+		if err != nil {
+			t.Fatalf("FailuresByIP: %v", err)
+		}
+		if count != want {
+			t.Errorf("%s: expected %d failures, got %d", ip, want, count)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	if got := Backoff(0); got != 0 {
+		t.Errorf("expected no wait without failures, got %v", got)
+	}
+	if got := Backoff(2); got != 2*time.Second {
+		t.Errorf("expected a short wait after 2 failures, got %v", got)
+	}
+	for _, failures := range []int64{11, 50, 1 << 40} {
+		if got := Backoff(failures); got != MaxBackoff {
+			t.Errorf("expected the capped wait after %d failures, got %v", failures, got)
+		}
+	}
+	for n := int64(1); n < 20; n++ {
+		if Backoff(n+1) < Backoff(n) {
+			t.Errorf("expected the wait to never shrink, %d failures gave %v then %v", n, Backoff(n), Backoff(n+1))
+		}
+	}
+}
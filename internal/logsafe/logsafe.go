@@ -0,0 +1,39 @@
+// Package logsafe masks personal data and secrets before they reach logs
+package logsafe
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// invite and session tokens are 43 base64url characters. ulids are 26, so
+	// record ids stay readable
+	tokenPattern = regexp.MustCompile(`[A-Za-z0-9_\-]{32,}`)
+)
+
+// Email keeps the first character and the domain, which is usually enough to
+// tell accounts apart while debugging
+func Email(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return "[redacted]"
+	}
+	return local[:1] + "***@" + domain
+}
+
+// Token keeps a short prefix so log lines about the same token can be matched
+func Token(token string) string {
+	if len(token) <= 4 {
+		return "[redacted]"
+	}
+	return token[:4] + "…"
+}
+
+// Redact masks every email address and token-like string in free text such as
+// wrapped error messages and request paths
+func Redact(s string) string {
+	s = emailPattern.ReplaceAllStringFunc(s, Email)
+	return tokenPattern.ReplaceAllStringFunc(s, Token)
+}
@@ -0,0 +1,31 @@
+package logsafe
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := map[string]struct {
+		in   string
+		want string
+	}{
+		"email":      {"no user ada.lovelace@example.com", "no user a***@example.com"},
+		"token":      {"/invite/Zm9vYmFyYmF6cXV4cXV1eGNvcmdlZ3JhdWx0Z2FycGx5", "/invite/Zm9v…"},
+		"ulid kept":  {"/medications/01J9Z3M8Q4W5X6Y7Z8A9B0C1D2", "/medications/01J9Z3M8Q4W5X6Y7Z8A9B0C1D2"},
+		"plain text": {"database is locked", "database is locked"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := Redact(tt.in); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmailWithoutLocalPart(t *testing.T) {
+	if got := Email("@example.com"); strings.Contains(got, "example") {
+		t.Errorf("expected fully redacted value, got %q", got)
+	}
+}
@@ -0,0 +1,107 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/clock"
+	"github.com/shelterkin/shelterkin/internal/database"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+)
+
+// Janitor periodically purges expired sessions and email changes and stale
+// login attempts, and checkpoints the WAL so it can't grow without bound
+type Janitor struct {
+	db                    *sql.DB
+	queries               *dbgen.Queries
+	interval              time.Duration
+	checkpointInterval    time.Duration
+	loginAttemptRetention time.Duration
+	clock                 clock.Clock
+}
+
+// NewJanitor takes the single-writer handle, so checkpoints queue behind
+// writes instead of competing with them
+func NewJanitor(db *sql.DB, clk clock.Clock, interval, checkpointInterval, loginAttemptRetention time.Duration) *Janitor {
+	return &Janitor{
+		db:                    db,
+		queries:               dbgen.New(db),
+		interval:              interval,
+		checkpointInterval:    checkpointInterval,
+		loginAttemptRetention: loginAttemptRetention,
+		clock:                 clk,
+	}
+}
+
+// Run purges once immediately, then purges and checkpoints on their own
+// tickers until ctx is cancelled
+func (j *Janitor) Run(ctx context.Context) {
+	if err := j.RunOnce(ctx); err != nil {
+		slog.Error("maintenance run failed", "error", err)
+	}
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	checkpointTicker := time.NewTicker(j.checkpointInterval)
+	defer checkpointTicker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := j.RunOnce(ctx); err != nil {
+				slog.Error("maintenance run failed", "error", err)
+			}
+		case <-checkpointTicker.C:
+			if err := j.Checkpoint(ctx); err != nil {
+				slog.Error("wal checkpoint failed", "error", err)
+			}
+		case <-ctx.Done():
+			slog.Info("maintenance janitor stopped")
+			return
+		}
+	}
+}
+
+func (j *Janitor) RunOnce(ctx context.Context) error {
+	now := j.clock.Now().UTC()
+
+	sessions, err := j.queries.DeleteExpiredSessions(ctx, now.Format(database.TimestampFormat))
+	if err != nil {
+		return fmt.Errorf("deleting expired sessions: %w", err)
+	}
+
+	emailChanges, err := j.queries.DeleteExpiredEmailChanges(ctx, now.Format(database.TimestampFormat))
+	if err != nil {
+		return fmt.Errorf("deleting expired email changes: %w", err)
+	}
+
+	cutoff := now.Add(-j.loginAttemptRetention).Format(database.TimestampFormat)
+	attempts, err := j.queries.DeleteOldLoginAttempts(ctx, cutoff)
+	if err != nil {
+		return fmt.Errorf("deleting old login attempts: %w", err)
+	}
+
+	slog.Info("maintenance run complete",
+		"expired_sessions_deleted", sessions,
+		"expired_email_changes_deleted", emailChanges,
+		"login_attempts_deleted", attempts,
+	)
+	return nil
+}
+
+func (j *Janitor) Checkpoint(ctx context.Context) error {
+	result, err := database.Checkpoint(ctx, j.db)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("wal checkpoint complete",
+		"busy", result.Busy,
+		"log_frames", result.LogFrames,
+		"checkpointed_frames", result.Checkpointed,
+	)
+	return nil
+}
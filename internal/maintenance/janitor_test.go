@@ -0,0 +1,125 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/testutil"
+)
+
+func seed(t *testing.T, db *sql.DB) {
+	t.Helper()
+	stmts := []string{
+		`INSERT INTO households (id, name_enc, encryption_salt) VALUES ('h1', 'enc', 'salt')`,
+		`INSERT INTO users (id, household_id, email_enc, email_hash, display_name_enc) VALUES ('u1', 'h1', 'enc', 'hash', 'enc')`,
+		`INSERT INTO sessions (id, user_id, household_id, expires_at) VALUES ('expired', 'u1', 'h1', '2025-01-01T00:00:00Z')`,
+		`INSERT INTO sessions (id, user_id, household_id, expires_at) VALUES ('current', 'u1', 'h1', '2025-03-01T00:00:00Z')`,
+		`INSERT INTO email_changes (id, user_id, household_id, new_email_enc, new_email_hash, token_hash, expires_at) VALUES ('expired', 'u1', 'h1', 'enc', 'new', 't1', '2025-01-01T00:00:00Z')`,
+		`INSERT INTO email_changes (id, user_id, household_id, new_email_enc, new_email_hash, token_hash, expires_at) VALUES ('pending', 'u1', 'h1', 'enc', 'new', 't2', '2025-02-02T00:00:00Z')`,
+		`INSERT INTO login_attempts (id, email_hash, ip_address, attempted_at) VALUES ('old', 'hash', '10.0.0.1', '2024-12-01T00:00:00Z')`,
+		`INSERT INTO login_attempts (id, email_hash, ip_address, attempted_at) VALUES ('recent', 'hash', '10.0.0.1', '2025-01-30T00:00:00Z')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seeding: %v", err)
+		}
+	}
+}
+
+func ids(t *testing.T, db *sql.DB, table string) []string {
+	t.Helper()
+	rows, err := db.Query("SELECT id FROM " + table + " ORDER BY id")
+	if err != nil {
+		t.Fatalf("querying %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("scanning %s: %v", table, err)
+		}
+		result = append(result, id)
+	}
+	return result
+}
+
+func fixedClock() *testutil.FakeClock {
+	return testutil.NewFakeClock(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func TestRunOnceDeletesExpiredRows(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	seed(t, db)
+
+	j := NewJanitor(db, fixedClock(), time.Hour, time.Hour, 30*24*time.Hour)
+
+	if err := j.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	if got := ids(t, db, "sessions"); len(got) != 1 || got[0] != "current" {
+		t.Errorf("expected only the current session to remain, got %v", got)
+	}
+	if got := ids(t, db, "login_attempts"); len(got) != 1 || got[0] != "recent" {
+		t.Errorf("expected only the recent attempt to remain, got %v", got)
+	}
+	if got := ids(t, db, "email_changes"); len(got) != 1 || got[0] != "pending" {
+		t.Errorf("expected only the pending email change to remain, got %v", got)
+	}
+}
+
+func TestRunPurgesOnStartAndStopsOnCancel(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	seed(t, db)
+
+	j := NewJanitor(db, fixedClock(), time.Hour, time.Hour, 30*24*time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		j.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(ids(t, db, "sessions")) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expired session was not purged")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
+
+func TestRunOnceExpiresSessionWhenClockAdvances(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	seed(t, db)
+	clk := fixedClock()
+	j := NewJanitor(db, clk, time.Hour, time.Hour, 30*24*time.Hour)
+
+	if err := j.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if got := ids(t, db, "sessions"); len(got) != 1 {
+		t.Fatalf("expected the current session to survive, got %v", got)
+	}
+
+	// the current session expires on 2025-03-01
+	clk.Advance(29 * 24 * time.Hour)
+	if err := j.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if got := ids(t, db, "sessions"); len(got) != 0 {
+		t.Errorf("expected the session to expire once the clock passed it, got %v", got)
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+const clientIPKey contextKey = "client_ip"
+
+// ClientIP works out the client address once per request so rate limiting
+// and logging agree on it. read it with GetClientIP
+func ClientIP(trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), clientIPKey, ExtractClientIP(r, trustedProxies))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetClientIP returns the address ClientIP stored, or "" without the
+// middleware
+func GetClientIP(ctx context.Context) string {
+	if ip, ok := ctx.Value(clientIPKey).(string); ok {
+		return ip
+	}
+	return ""
+}
+
+// ExtractClientIP returns the connecting address, unless that is a trusted
+// proxy. then X-Forwarded-For is walked from the right, since each proxy
+// appends to it, and the first address not in trustedProxies is the client.
+// entries left of that were supplied by the client and can't be believed
+func ExtractClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	addr, err := netip.ParseAddr(remote)
+	if err != nil || !trusted(addr, trustedProxies) {
+		return remote
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// a malformed hop means the chain can't be followed any further
+			break
+		}
+		addr = hop.Unmap()
+		if !trusted(addr, trustedProxies) {
+			break
+		}
+	}
+	return addr.String()
+}
+
+func trusted(addr netip.Addr, prefixes []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// AllowedHosts rejects requests whose Host header isn't one of hosts with a
+// 400, so a spoofed Host can't end up in the absolute links the app builds.
+// hosts are host or host:port as they appear in BASE_URL. a loopback entry
+// allows every loopback host on any port, which keeps local development
+// working however the server is reached. paths in exempt skip the check, for
+// probes that address the server by its ip
+func AllowedHosts(hosts []string, exempt ...string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(hosts))
+	allowLoopback := false
+	for _, host := range hosts {
+		host = normalizeHost(host)
+		allowed[host] = true
+		if isLoopbackHost(host) {
+			allowLoopback = true
+		}
+	}
+	skip := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		skip[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host := normalizeHost(r.Host)
+			if !allowed[host] && !(allowLoopback && isLoopbackHost(host)) && !skip[r.URL.Path] {
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// normalizeHost lowercases host and drops a trailing dot from the name, so
+// "Example.com.:443" and "example.com:443" compare equal
+func normalizeHost(host string) string {
+	host = strings.ToLower(host)
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return strings.TrimSuffix(host, ".")
+	}
+	return net.JoinHostPort(strings.TrimSuffix(name, "."), port)
+}
+
+func isLoopbackHost(host string) bool {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
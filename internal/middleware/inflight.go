@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// InFlight tracks the requests that are still being served so shutdown can
+// report what it is waiting on
+type InFlight struct {
+	mu     sync.Mutex
+	nextID uint64
+	active map[uint64]string
+}
+
+func NewInFlight() *InFlight {
+	return &InFlight{active: make(map[uint64]string)}
+}
+
+func (f *InFlight) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := f.add(r.Method + " " + r.URL.Path)
+		defer f.remove(id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (f *InFlight) Count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.active)
+}
+
+// Routes returns the method and path of every active request, sorted
+func (f *InFlight) Routes() []string {
+	f.mu.Lock()
+	routes := make([]string, 0, len(f.active))
+	for _, route := range f.active {
+		routes = append(routes, route)
+	}
+	f.mu.Unlock()
+
+	sort.Strings(routes)
+	return routes
+}
+
+func (f *InFlight) add(route string) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	f.active[f.nextID] = route
+	return f.nextID
+}
+
+func (f *InFlight) remove(id uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.active, id)
+}
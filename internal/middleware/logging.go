@@ -1,14 +1,21 @@
 package middleware
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"time"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/logsafe"
 )
 
+const requestLogKey contextKey = "request_log"
+
 type statusRecorder struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode   int
+	bytesWritten int
 }
 
 func (r *statusRecorder) WriteHeader(code int) {
@@ -16,19 +23,75 @@ func (r *statusRecorder) WriteHeader(code int) {
 	r.ResponseWriter.WriteHeader(code)
 }
 
-func Logging(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytesWritten += n
+	return n, err
+}
+
+// Flush keeps streamed responses streaming through the recorder
+func (r *statusRecorder) Flush() {
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// requestLog collects fields set by inner handlers, since context values they
+// add are not visible to Logging once the handler returns
+type requestLog struct {
+	err         *apperror.Error
+	userID      string
+	householdID string
+}
+
+// RecordError attaches err to the request log line written by Logging
+func RecordError(ctx context.Context, err *apperror.Error) {
+	if log, ok := ctx.Value(requestLogKey).(*requestLog); ok {
+		log.err = err
+	}
+}
+
+// RecordUser attaches the authenticated user to the request log line written
+// by Logging
+func RecordUser(ctx context.Context, userID, householdID string) {
+	if log, ok := ctx.Value(requestLogKey).(*requestLog); ok {
+		log.userID = userID
+		log.householdID = householdID
+	}
+}
+
+// Logging writes one line per request. query strings are left out unless
+// logQueryStrings is set because they can carry invite and reset tokens
+func Logging(logQueryStrings bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			log := &requestLog{}
 
-		next.ServeHTTP(recorder, r)
+			next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), requestLogKey, log)))
 
-		slog.Info("request",
-			"method", r.Method,
-			"path", r.URL.Path,
-			"status", recorder.statusCode,
-			"duration_ms", time.Since(start).Milliseconds(),
-			"request_id", GetRequestID(r.Context()),
-		)
-	})
+			attrs := []any{
+				"method", r.Method,
+				"path", logsafe.Redact(r.URL.Path),
+				"status", recorder.statusCode,
+				"bytes", recorder.bytesWritten,
+				"duration_ms", time.Since(start).Milliseconds(),
+				"request_id", GetRequestID(r.Context()),
+				"client_ip", GetClientIP(r.Context()),
+			}
+			if logQueryStrings && r.URL.RawQuery != "" {
+				attrs = append(attrs, "query", logsafe.Redact(r.URL.RawQuery))
+			}
+			if log.userID != "" {
+				attrs = append(attrs, "user_id", log.userID, "household_id", log.householdID)
+			}
+			if log.err != nil {
+				attrs = append(attrs, log.err.LogAttrs()...)
+			}
+			slog.Info("request", attrs...)
+		})
+	}
 }
@@ -1,9 +1,18 @@
 package middleware
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
 )
 
 func TestRequestIDSetsHeader(t *testing.T) {
@@ -47,7 +56,7 @@ func TestRequestIDUnique(t *testing.T) {
 }
 
 func TestSecurityHeadersSet(t *testing.T) {
-	handler := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := SecurityHeaders(false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -76,8 +85,143 @@ func TestSecurityHeadersSet(t *testing.T) {
 	}
 }
 
+func TestSecurityHeadersReportOnly(t *testing.T) {
+	handler := SecurityHeaders(true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if got := rec.Header().Get("Content-Security-Policy"); got != "" {
+		t.Errorf("expected no enforcing policy in report-only mode, got %q", got)
+	}
+	csp := rec.Header().Get("Content-Security-Policy-Report-Only")
+	if !strings.Contains(csp, "default-src 'self'") || !strings.HasSuffix(csp, "report-uri "+CSPReportPath) {
+		t.Errorf("expected the policy with a report-uri, got %q", csp)
+	}
+}
+
+func TestSecurityHeadersNonce(t *testing.T) {
+	var seen []string
+	handler := SecurityHeaders(false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, CSPNonce(r.Context()))
+	}))
+
+	var headers []string
+	for range 2 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		headers = append(headers, rec.Header().Get("Content-Security-Policy"))
+	}
+
+	if seen[0] == "" || seen[0] == seen[1] {
+		t.Fatalf("expected a unique nonce per request, got %q", seen)
+	}
+	for i, csp := range headers {
+		if !strings.Contains(csp, "script-src 'self' 'nonce-"+seen[i]+"'") || !strings.Contains(csp, "style-src 'self' 'nonce-"+seen[i]+"'") {
+			t.Errorf("expected nonce %q in CSP, got %q", seen[i], csp)
+		}
+		if strings.Contains(csp, "unsafe-inline") {
+			t.Errorf("expected unsafe-inline to be dropped, got %q", csp)
+		}
+	}
+}
+
+func TestAllowedHosts(t *testing.T) {
+	handler := AllowedHosts([]string{"shelterkin.example.com"}, "/livez")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		host string
+		path string
+		want int
+	}{
+		{"shelterkin.example.com", "/", http.StatusOK},
+		{"Shelterkin.Example.com.", "/", http.StatusOK},
+		{"evil.example.com", "/", http.StatusBadRequest},
+		{"shelterkin.example.com:8443", "/", http.StatusBadRequest},
+		{"localhost:8080", "/", http.StatusBadRequest},
+		{"10.0.0.5:8080", "/livez", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("Host %q on %s: expected %d, got %d", tt.host, tt.path, tt.want, rec.Code)
+		}
+	}
+}
+
+func TestAllowedHostsLoopbackForDevelopment(t *testing.T) {
+	handler := AllowedHosts([]string{"localhost:8080"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for host, want := range map[string]int{
+		"localhost:8080":   http.StatusOK,
+		"localhost:3000":   http.StatusOK,
+		"127.0.0.1:8080":   http.StatusOK,
+		"[::1]:8080":       http.StatusOK,
+		"evil.example.com": http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != want {
+			t.Errorf("Host %q: expected %d, got %d", host, want, rec.Code)
+		}
+	}
+}
+
+func TestClientIPMatchesExtractor(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		want      string
+	}{
+		{"direct", "203.0.113.7:51234", "", "203.0.113.7"},
+		{"untrusted peer cannot forward", "203.0.113.7:51234", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:443", "198.51.100.1", "198.51.100.1"},
+		{"proxy chain", "10.0.0.2:443", "198.51.100.1, 10.0.0.3", "198.51.100.1"},
+		{"spoofed prefix ignored", "10.0.0.2:443", "192.0.2.66, 198.51.100.1", "198.51.100.1"},
+		{"trusted proxy without header", "10.0.0.2:443", "", "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+
+			var got string
+			handler := ClientIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = GetClientIP(r.Context())
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("expected %q in context, got %q", tt.want, got)
+			}
+			if extracted := ExtractClientIP(req, trusted); got != extracted {
+				t.Errorf("context has %q but the extractor returns %q", got, extracted)
+			}
+		})
+	}
+}
+
+func TestGetClientIPWithoutMiddleware(t *testing.T) {
+	if ip := GetClientIP(context.Background()); ip != "" {
+		t.Errorf("expected empty client ip, got %q", ip)
+	}
+}
+
 func TestLoggingRecordsStatus(t *testing.T) {
-	handler := Logging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := Logging(false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusCreated)
 	}))
 
@@ -90,6 +234,195 @@ func TestLoggingRecordsStatus(t *testing.T) {
 	}
 }
 
+func TestLoggingRecordsErrorResource(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	handler := Logging(false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		RecordError(r.Context(), apperror.NotFound("medication", "abc123"))
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	req := httptest.NewRequest("GET", "/medications/abc123", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("decoding log line: %v", err)
+	}
+	if entry["resource"] != "abc123" {
+		t.Errorf("expected resource 'abc123' in log, got %v", entry["resource"])
+	}
+	if entry["error"] != "medication not found" {
+		t.Errorf("expected error message in log, got %v", entry["error"])
+	}
+}
+
+func TestLoggingRecordsUserAndBytes(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	handler := Logging(false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		RecordUser(r.Context(), "user-1", "household-1")
+		w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest("GET", "/dashboard?token=secret", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("decoding log line: %v", err)
+	}
+	if entry["user_id"] != "user-1" || entry["household_id"] != "household-1" {
+		t.Errorf("expected user and household in log, got %v", entry)
+	}
+	if entry["bytes"] != float64(5) {
+		t.Errorf("expected 5 bytes in log, got %v", entry["bytes"])
+	}
+	if _, ok := entry["query"]; ok {
+		t.Errorf("query string must not be logged by default, got %v", entry["query"])
+	}
+}
+
+func TestLoggingRedactsTokensInPath(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	handler := Logging(false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/invite/Zm9vYmFyYmF6cXV4cXV1eGNvcmdlZ3JhdWx0Z2FycGx5", nil))
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("decoding log line: %v", err)
+	}
+	if entry["path"] != "/invite/Zm9v…" {
+		t.Errorf("expected token to be redacted from path, got %v", entry["path"])
+	}
+}
+
+func TestLoggingQueryStringsOptIn(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	handler := Logging(true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/search?q=ada", nil))
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("decoding log line: %v", err)
+	}
+	if entry["query"] != "q=ada" {
+		t.Errorf("expected query string in log, got %v", entry["query"])
+	}
+	if _, ok := entry["user_id"]; ok {
+		t.Errorf("anonymous request should not log a user, got %v", entry["user_id"])
+	}
+}
+
+func TestTimeoutAbortsSlowHandler(t *testing.T) {
+	cancelled := make(chan struct{})
+	handler := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(cancelled)
+		w.Write([]byte("too late"))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/slow", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "<html") || strings.Contains(rec.Body.String(), "too late") {
+		t.Errorf("expected the full error page only, got %s", rec.Body.String())
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("handler context was never cancelled")
+	}
+}
+
+func TestTimeoutRendersFragmentForHTMX(t *testing.T) {
+	handler := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+
+	req := httptest.NewRequest("POST", "/slow", nil)
+	req.Header.Set("HX-Request", "true")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "<html") || !strings.Contains(body, `role="alert"`) {
+		t.Errorf("expected an alert fragment, got %s", body)
+	}
+}
+
+func TestTimeoutPassesFastHandler(t *testing.T) {
+	handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusCreated {
+		t.Errorf("expected status 201, got %d", rec.Code)
+	}
+}
+
+func TestTimeoutStreamsFlushedWrites(t *testing.T) {
+	flushed := make(chan struct{})
+	release := make(chan struct{})
+	handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("event: ping\n\n"))
+		w.(http.Flusher).Flush()
+		close(flushed)
+		<-release
+	}))
+	handler = Logging(false)(handler)
+
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/events", nil))
+		close(done)
+	}()
+
+	<-flushed
+	// the first event reached the client while the handler is still running
+	if !rec.Flushed || rec.Body.String() != "event: ping\n\n" {
+		t.Errorf("expected the event to be flushed before the handler returned, got flushed=%v body=%q", rec.Flushed, rec.Body.String())
+	}
+	close(release)
+	<-done
+}
+
+func TestTimeoutKeepsResponseStartedBeforeDeadline(t *testing.T) {
+	handler := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		<-r.Context().Done()
+		w.Write([]byte(" rest"))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "partial rest" {
+		t.Errorf("expected the started response to finish, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
 func TestRecoverCatchesPanic(t *testing.T) {
 	handler := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		panic("test panic")
@@ -113,3 +446,30 @@ func TestGetRequestIDWithoutMiddleware(t *testing.T) {
 		t.Errorf("expected empty request ID without middleware, got %q", id)
 	}
 }
+
+func TestInFlightTracksActiveRequests(t *testing.T) {
+	inFlight := NewInFlight()
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	handler := inFlight.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/busy", nil))
+		close(done)
+	}()
+
+	<-entered
+	if routes := inFlight.Routes(); len(routes) != 1 || routes[0] != "POST /busy" {
+		t.Errorf("expected [POST /busy], got %v", routes)
+	}
+
+	close(release)
+	<-done
+	if n := inFlight.Count(); n != 0 {
+		t.Errorf("expected 0 in flight after completion, got %d", n)
+	}
+}
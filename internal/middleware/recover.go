@@ -1,9 +1,12 @@
 package middleware
 
 import (
+	"fmt"
 	"log/slog"
 	"net/http"
 	"runtime/debug"
+
+	"github.com/shelterkin/shelterkin/internal/logsafe"
 )
 
 func Recover(next http.Handler) http.Handler {
@@ -11,9 +14,9 @@ func Recover(next http.Handler) http.Handler {
 		defer func() {
 			if rec := recover(); rec != nil {
 				slog.Error("panic recovered",
-					"panic", rec,
+					"panic", logsafe.Redact(fmt.Sprint(rec)),
 					"stack", string(debug.Stack()),
-					"path", r.URL.Path,
+					"path", logsafe.Redact(r.URL.Path),
 					"method", r.Method,
 					"request_id", GetRequestID(r.Context()),
 				)
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/shelterkin/shelterkin/components"
+	"github.com/shelterkin/shelterkin/internal/httpx"
+)
+
+const (
+	timeoutTitle   = "Request timed out"
+	timeoutMessage = "This is taking longer than expected. Please try again."
+)
+
+// Timeout gives each request a context deadline of d, so database calls made
+// with the request context are cancelled, and answers 503 when the deadline
+// passes before the handler has sent anything. responses are not buffered,
+// so streamed and flushed responses go out as they are written; once the
+// deadline has passed, writes are dropped unless the response had already
+// started. a zero d disables the limit. the error bodies are rendered once up
+// front, so they carry no csp nonce and the layout's inline htmx config does
+// not run on them
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		page := renderString(components.ErrorContent(false, timeoutTitle, timeoutMessage))
+		fragment := renderString(components.ErrorContent(true, timeoutTitle, timeoutMessage))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			tw := &timeoutWriter{ResponseWriter: w, ctx: ctx}
+			next.ServeHTTP(tw, r.WithContext(ctx))
+
+			if tw.started || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return
+			}
+			body := page
+			if httpx.Negotiate(r) == httpx.HTMX {
+				body = fragment
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, body)
+		})
+	}
+}
+
+// timeoutWriter passes writes straight through until the deadline. after it,
+// a response that hasn't started is held back for the 503 instead
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx     context.Context
+	started bool
+}
+
+func (tw *timeoutWriter) expired() bool {
+	return !tw.started && tw.ctx.Err() != nil
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	if tw.expired() {
+		return
+	}
+	tw.started = true
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	if tw.expired() {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.started = true
+	return tw.ResponseWriter.Write(b)
+}
+
+func (tw *timeoutWriter) Flush() {
+	if tw.expired() {
+		return
+	}
+	tw.started = true
+	http.NewResponseController(tw.ResponseWriter).Flush()
+}
+
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+func renderString(c templ.Component) string {
+	var b strings.Builder
+	c.Render(context.Background(), &b)
+	return b.String()
+}
@@ -0,0 +1,103 @@
+// Package paginate pages through ulid-keyed lists with opaque cursors
+package paginate
+
+import (
+	"encoding/base64"
+	"net/url"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/ulid"
+)
+
+const DefaultLimit = 25
+
+// Keyset is the page a request asked for. After pages forward from a row,
+// Before pages back from one; neither means the first page. queries should
+// fetch Limit+1 rows, ordered by id ascending when paging forward and
+// descending when paging back, and hand them to Window
+type Keyset struct {
+	After  string
+	Before string
+	Limit  int
+}
+
+type Links struct {
+	Prev string
+	Next string
+}
+
+// Parse reads the after and before cursors from a request's query string. a
+// limit of zero uses DefaultLimit
+func Parse(query url.Values, limit int) (Keyset, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	k := Keyset{Limit: limit}
+	var err error
+	if k.After, err = decodeCursor(query.Get("after")); err != nil {
+		return Keyset{}, err
+	}
+	if k.Before, err = decodeCursor(query.Get("before")); err != nil {
+		return Keyset{}, err
+	}
+	if k.After != "" && k.Before != "" {
+		return Keyset{}, apperror.Validation("cursor", "This page link is invalid")
+	}
+	return k, nil
+}
+
+func (k Keyset) backward() bool {
+	return k.Before != ""
+}
+
+// Window trims the extra lookahead row and puts a backward page back into
+// ascending order. more reports whether rows exist beyond the page in the
+// direction it was fetched
+func Window[T any](k Keyset, rows []T) (page []T, more bool) {
+	more = len(rows) > k.Limit
+	if more {
+		rows = rows[:k.Limit]
+	}
+	if k.backward() {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	return rows, more
+}
+
+// Links builds prev and next urls from the ids of the first and last rows on
+// the page. an empty link means there is no page that way
+func (k Keyset) Links(path, firstID, lastID string, more bool) Links {
+	if firstID == "" {
+		return Links{}
+	}
+
+	var links Links
+	hasPrev, hasNext := k.After != "", more
+	if k.backward() {
+		hasPrev, hasNext = more, true
+	}
+	if hasPrev {
+		links.Prev = pageURL(path, "before", firstID)
+	}
+	if hasNext {
+		links.Next = pageURL(path, "after", lastID)
+	}
+	return links
+}
+
+func pageURL(path, param, id string) string {
+	return path + "?" + url.Values{param: {base64.RawURLEncoding.EncodeToString([]byte(id))}}.Encode()
+}
+
+func decodeCursor(cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	id, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !ulid.Valid(string(id)) {
+		return "", apperror.Validation("cursor", "This page link is invalid")
+	}
+	return string(id), nil
+}
@@ -0,0 +1,87 @@
+package paginate
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/shelterkin/shelterkin/internal/ulid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := ulid.New()
+	links := Keyset{Limit: 2}.Links("/members", ulid.New(), id, true)
+
+	next, err := url.Parse(links.Next)
+	if err != nil {
+		t.Fatalf("parsing next link: %v", err)
+	}
+	if strings.Contains(links.Next, id) {
+		t.Errorf("expected an opaque cursor, got %s", links.Next)
+	}
+
+	k, err := Parse(next.Query(), 2)
+	if err != nil {
+		t.Fatalf("parsing cursor: %v", err)
+	}
+	if k.After != id || k.Before != "" {
+		t.Errorf("expected after=%s, got %+v", id, k)
+	}
+}
+
+func TestParseRejectsBadCursors(t *testing.T) {
+	for _, query := range []string{"after=not-base64!", "after=Zm9v", "after=" + url.QueryEscape("x") + "&before=y"} {
+		values, _ := url.ParseQuery(query)
+		if _, err := Parse(values, 0); err == nil {
+			t.Errorf("expected error for %q", query)
+		}
+	}
+
+	k, err := Parse(url.Values{}, 0)
+	if err != nil || k.Limit != DefaultLimit {
+		t.Errorf("expected first page with default limit, got %+v, %v", k, err)
+	}
+}
+
+func TestLinksAtBoundaries(t *testing.T) {
+	first, last := ulid.New(), ulid.New()
+
+	tests := map[string]struct {
+		k        Keyset
+		more     bool
+		wantPrev bool
+		wantNext bool
+	}{
+		"only page":          {Keyset{Limit: 2}, false, false, false},
+		"first of many":      {Keyset{Limit: 2}, true, false, true},
+		"middle going ahead": {Keyset{After: first, Limit: 2}, true, true, true},
+		"last page":          {Keyset{After: first, Limit: 2}, false, true, false},
+		"back to first":      {Keyset{Before: last, Limit: 2}, false, false, true},
+		"middle going back":  {Keyset{Before: last, Limit: 2}, true, true, true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			links := tt.k.Links("/members", first, last, tt.more)
+			if (links.Prev != "") != tt.wantPrev || (links.Next != "") != tt.wantNext {
+				t.Errorf("got prev=%q next=%q", links.Prev, links.Next)
+			}
+		})
+	}
+
+	if links := (Keyset{After: first, Limit: 2}).Links("/members", "", "", false); links != (Links{}) {
+		t.Errorf("expected no links for an empty page, got %+v", links)
+	}
+}
+
+func TestWindowTrimsAndReverses(t *testing.T) {
+	rows, more := Window(Keyset{Limit: 2}, []int{1, 2, 3})
+	if !more || len(rows) != 2 || rows[0] != 1 || rows[1] != 2 {
+		t.Errorf("forward window got %v more=%v", rows, more)
+	}
+
+	// backward queries come back newest first
+	rows, more = Window(Keyset{Before: ulid.New(), Limit: 2}, []int{5, 4})
+	if more || rows[0] != 4 || rows[1] != 5 {
+		t.Errorf("backward window got %v more=%v", rows, more)
+	}
+}
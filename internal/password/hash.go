@@ -0,0 +1,181 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var ErrUnknownHash = errors.New("unrecognized password hash format")
+
+// Hasher is one password hashing algorithm. hashes carry their own algorithm
+// prefix ($2b$ for bcrypt, $argon2id$ for argon2id), so several can coexist
+// in the users table while accounts migrate
+type Hasher interface {
+	Hash(pw string) (string, error)
+	Verify(hash, pw string) (bool, error)
+	// Handles reports whether hash was produced by this algorithm
+	Handles(hash string) bool
+	// Outdated reports whether hash was produced with weaker parameters
+	// than this hasher would use today
+	Outdated(hash string) bool
+}
+
+// Hashers verifies against every supported algorithm but hashes new
+// passwords with the preferred one
+type Hashers struct {
+	preferred Hasher
+	all       []Hasher
+}
+
+// NewHashers picks the algorithm new hashes use, by its config name
+func NewHashers(preferred string) (*Hashers, error) {
+	bc, a2 := Bcrypt{Cost: bcryptCost}, DefaultArgon2id()
+	h := &Hashers{all: []Hasher{bc, a2}}
+	switch preferred {
+	case AlgorithmBcrypt:
+		h.preferred = bc
+	case AlgorithmArgon2id:
+		h.preferred = a2
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q", preferred)
+	}
+	return h, nil
+}
+
+func (h *Hashers) Hash(pw string) (string, error) {
+	return h.preferred.Hash(pw)
+}
+
+// Verify checks pw against hash whatever algorithm produced it. rehash is set
+// on a match when the caller should store a fresh Hash of pw, which is the
+// only moment an old hash can be upgraded since it needs the plaintext
+func (h *Hashers) Verify(hash, pw string) (ok, rehash bool, err error) {
+	for _, hasher := range h.all {
+		if !hasher.Handles(hash) {
+			continue
+		}
+		ok, err := hasher.Verify(hash, pw)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		rehash = hasher != h.preferred || hasher.Outdated(hash)
+		return true, rehash, nil
+	}
+	return false, false, ErrUnknownHash
+}
+
+const bcryptCost = 12
+
+type Bcrypt struct {
+	Cost int
+}
+
+func (b Bcrypt) Hash(pw string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(pw), b.Cost)
+	if err != nil {
+		return "", fmt.Errorf("hashing password: %w", err)
+	}
+	return string(hash), nil
+}
+
+func (b Bcrypt) Verify(hash, pw string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(pw))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("verifying password: %w", err)
+	}
+	return true, nil
+}
+
+func (b Bcrypt) Handles(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (b Bcrypt) Outdated(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < b.Cost
+}
+
+// Argon2id stores hashes in the PHC string format the reference
+// implementation uses: $argon2id$v=19$m=<KiB>,t=<passes>,p=<lanes>$<salt>$<key>
+type Argon2id struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+	SaltLen int
+	KeyLen  uint32
+}
+
+// DefaultArgon2id follows the OWASP minimum for argon2id password storage
+func DefaultArgon2id() Argon2id {
+	return Argon2id{Memory: 19 * 1024, Time: 2, Threads: 1, SaltLen: 16, KeyLen: 32}
+}
+
+const argon2Prefix = "$argon2id$"
+
+func (a Argon2id) Hash(pw string) (string, error) {
+	salt := make([]byte, a.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generating salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(pw), salt, a.Time, a.Memory, a.Threads, a.KeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version, a.Memory, a.Time, a.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a Argon2id) Verify(hash, pw string) (bool, error) {
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+	got := argon2.IDKey([]byte(pw), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(got, key) == 1, nil
+}
+
+func (a Argon2id) Handles(hash string) bool {
+	return strings.HasPrefix(hash, argon2Prefix)
+}
+
+func (a Argon2id) Outdated(hash string) bool {
+	params, _, _, err := parseArgon2id(hash)
+	return err != nil || params.Memory < a.Memory || params.Time < a.Time || params.Threads < a.Threads
+}
+
+func parseArgon2id(hash string) (params Argon2id, salt, key []byte, err error) {
+	parts := strings.Split(strings.TrimPrefix(hash, argon2Prefix), "$")
+	if len(parts) != 4 {
+		return params, nil, nil, ErrUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[0], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[0])
+	}
+	if _, err := fmt.Sscanf(parts[1], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, fmt.Errorf("parsing argon2 parameters: %w", err)
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[2]); err != nil {
+		return params, nil, nil, fmt.Errorf("decoding argon2 salt: %w", err)
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[3]); err != nil {
+		return params, nil, nil, fmt.Errorf("decoding argon2 key: %w", err)
+	}
+	if len(key) == 0 {
+		return params, nil, nil, ErrUnknownHash
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,95 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHashersVerifyBothAlgorithms(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmBcrypt, AlgorithmArgon2id} {
+		hashers, err := NewHashers(algorithm)
+		if err != nil {
+			t.Fatalf("%s: %v", algorithm, err)
+		}
+
+		hash, err := hashers.Hash("correct horse battery staple")
+		if err != nil {
+			t.Fatalf("%s: hashing: %v", algorithm, err)
+		}
+
+		ok, rehash, err := hashers.Verify(hash, "correct horse battery staple")
+		if err != nil || !ok {
+			t.Errorf("%s: expected the right password to verify, got ok=%v err=%v", algorithm, ok, err)
+		}
+		if rehash {
+			t.Errorf("%s: a fresh hash from the preferred algorithm should not need rehashing", algorithm)
+		}
+
+		ok, _, err = hashers.Verify(hash, "wrong horse battery staple")
+		if err != nil || ok {
+			t.Errorf("%s: expected the wrong password to be rejected, got ok=%v err=%v", algorithm, ok, err)
+		}
+	}
+}
+
+func TestHashersUpgradeBcryptToArgon2id(t *testing.T) {
+	legacy, err := Bcrypt{Cost: bcryptCost}.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("hashing: %v", err)
+	}
+
+	hashers, err := NewHashers(AlgorithmArgon2id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, rehash, err := hashers.Verify(legacy, "correct horse battery staple")
+	if err != nil || !ok {
+		t.Fatalf("expected the bcrypt hash to still verify, got ok=%v err=%v", ok, err)
+	}
+	if !rehash {
+		t.Fatal("expected a bcrypt hash to be flagged for rehashing when argon2id is preferred")
+	}
+
+	upgraded, err := hashers.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("rehashing: %v", err)
+	}
+	if !strings.HasPrefix(upgraded, "$argon2id$v=19$") {
+		t.Errorf("expected an argon2id hash, got %q", upgraded)
+	}
+}
+
+func TestHashersRehashWeakerParameters(t *testing.T) {
+	weak := DefaultArgon2id()
+	weak.Memory = 8 * 1024
+	hash, err := weak.Hash("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashers, err := NewHashers(AlgorithmArgon2id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, rehash, err := hashers.Verify(hash, "pw")
+	if err != nil || !ok || !rehash {
+		t.Errorf("expected a weaker argon2id hash to verify and need rehashing, got ok=%v rehash=%v err=%v", ok, rehash, err)
+	}
+}
+
+func TestHashersRejectUnknownFormats(t *testing.T) {
+	hashers, err := NewHashers(AlgorithmArgon2id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hash := range []string{"", "plaintext", "$argon2id$v=19$m=1,t=1,p=1$c2FsdA$"} {
+		if ok, _, err := hashers.Verify(hash, "pw"); ok || !errors.Is(err, ErrUnknownHash) {
+			t.Errorf("%q: expected ErrUnknownHash, got ok=%v err=%v", hash, ok, err)
+		}
+	}
+
+	if _, err := NewHashers("md5"); err == nil {
+		t.Error("expected an unknown algorithm name to be rejected")
+	}
+}
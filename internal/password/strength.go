@@ -0,0 +1,155 @@
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+)
+
+const (
+	// MinLength is the floor for every policy, and the default
+	MinLength = 8
+	// MinScore is the weakest score Validate accepts
+	MinScore = 2
+)
+
+//go:embed common.txt
+var commonList string
+
+var common = func() map[string]struct{} {
+	set := make(map[string]struct{})
+	scanner := bufio.NewScanner(strings.NewReader(commonList))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			set[line] = struct{}{}
+		}
+	}
+	return set
+}()
+
+// Policy is the password rules a deployment enforces
+type Policy struct {
+	MinLength int
+}
+
+// NewPolicy requires at least minLength characters, and never fewer than
+// MinLength
+func NewPolicy(minLength int) Policy {
+	return Policy{MinLength: max(minLength, MinLength)}
+}
+
+var defaultPolicy = NewPolicy(MinLength)
+
+// Strength scores pw against the default policy
+func Strength(pw string) (int, []string) {
+	return defaultPolicy.Strength(pw)
+}
+
+// Validate checks pw against the default policy
+func Validate(pw string) *apperror.Error {
+	return defaultPolicy.Validate(pw)
+}
+
+// Strength scores pw from 0 to 4 and explains what would make it stronger
+func (p Policy) Strength(pw string) (int, []string) {
+	if _, ok := common[strings.ToLower(pw)]; ok {
+		return 0, []string{"This password is too common, choose something less predictable"}
+	}
+
+	length := utf8.RuneCountInString(pw)
+	classes := characterClasses(pw)
+
+	var reasons []string
+	if length < p.MinLength {
+		reasons = append(reasons, fmt.Sprintf("Use at least %d characters", p.MinLength))
+	}
+	if classes < 3 {
+		reasons = append(reasons, "Mix upper and lower case letters, numbers and symbols")
+	}
+	if length < 12 && p.MinLength < 12 {
+		reasons = append(reasons, "Use 12 or more characters for a stronger password")
+	}
+
+	// too short is never acceptable however varied the characters are
+	if length < p.MinLength {
+		return 0, reasons
+	}
+
+	score := 1
+	if length >= 12 {
+		score++
+	}
+	if classes >= 3 {
+		score++
+	}
+	if length >= 16 || classes == 4 {
+		score++
+	}
+
+	return score, reasons
+}
+
+// Validate rejects passwords scoring below MinScore, reporting the most
+// useful reason first
+func (p Policy) Validate(pw string) *apperror.Error {
+	score, reasons := p.Strength(pw)
+	if score >= MinScore {
+		return nil
+	}
+	return apperror.Validation("password", reasons[0])
+}
+
+// minPersonalLength skips very short names and local parts, which would
+// otherwise reject any password containing common letter pairs
+const minPersonalLength = 3
+
+// ValidatePersonal rejects passwords that contain the email's local part or
+// the display name, ignoring case
+func ValidatePersonal(pw, email, displayName string) *apperror.Error {
+	lower := strings.ToLower(pw)
+	localPart, _, _ := strings.Cut(email, "@")
+
+	checks := []struct {
+		value   string
+		message string
+	}{
+		{localPart, "Your password can't contain your email address"},
+		{displayName, "Your password can't contain your name"},
+	}
+	for _, check := range checks {
+		value := strings.ToLower(strings.TrimSpace(check.value))
+		if utf8.RuneCountInString(value) >= minPersonalLength && strings.Contains(lower, value) {
+			return apperror.Validation("password", check.message)
+		}
+	}
+	return nil
+}
+
+func characterClasses(pw string) int {
+	var lower, upper, digit, other bool
+	for _, r := range pw {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	count := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
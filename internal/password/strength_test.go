@@ -0,0 +1,101 @@
+package password
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStrongPasswordPasses(t *testing.T) {
+	score, reasons := Strength("Correct-Horse-Battery-9")
+	if score != 4 {
+		t.Errorf("expected score 4, got %d (%v)", score, reasons)
+	}
+	if err := Validate("Correct-Horse-Battery-9"); err != nil {
+		t.Errorf("expected strong password to pass, got %v", err)
+	}
+}
+
+func TestBlocklistedPasswordFails(t *testing.T) {
+	for _, pw := range []string{"password", "Password", "qwerty123"} {
+		score, _ := Strength(pw)
+		if score != 0 {
+			t.Errorf("%s: expected score 0, got %d", pw, score)
+		}
+		err := Validate(pw)
+		if err == nil || !strings.Contains(err.Message, "too common") {
+			t.Errorf("%s: expected common password error, got %v", pw, err)
+		}
+	}
+}
+
+func TestWeakPasswordMessagesAreActionable(t *testing.T) {
+	tests := []struct {
+		pw   string
+		want string
+	}{
+		{"aB3$", "at least 8 characters"},
+		{"abcdefghij", "Mix upper and lower case"},
+	}
+
+	for _, tt := range tests {
+		err := Validate(tt.pw)
+		if err == nil {
+			t.Errorf("%s: expected rejection", tt.pw)
+			continue
+		}
+		if err.Field != "password" || !strings.Contains(err.Message, tt.want) {
+			t.Errorf("%s: expected message containing %q, got %q", tt.pw, tt.want, err.Message)
+		}
+	}
+}
+
+func TestPolicyCustomMinimum(t *testing.T) {
+	policy := NewPolicy(12)
+
+	err := policy.Validate("Correct-Hor")
+	if err == nil || !strings.Contains(err.Message, "at least 12 characters") {
+		t.Errorf("expected an 11 character password to be rejected, got %v", err)
+	}
+	if err := policy.Validate("Correct-Hors"); err != nil {
+		t.Errorf("expected a 12 character password to pass, got %v", err)
+	}
+}
+
+func TestPolicyMinimumHasFloor(t *testing.T) {
+	if got := NewPolicy(4).MinLength; got != MinLength {
+		t.Errorf("expected the minimum to be raised to %d, got %d", MinLength, got)
+	}
+}
+
+func TestValidatePersonal(t *testing.T) {
+	tests := []struct {
+		name string
+		pw   string
+		want string
+	}{
+		{"equals display name", "Ada Lovelace", "your name"},
+		{"contains email local part", "2024-ADA.LOVELACE!", "your email"},
+		{"unrelated", "Correct-Horse-Battery-9", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePersonal(tt.pw, "ada.lovelace@example.com", "Ada Lovelace")
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("expected password to pass, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Field != "password" || !strings.Contains(err.Message, tt.want) {
+				t.Fatalf("expected rejection mentioning %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidatePersonalIgnoresShortValues(t *testing.T) {
+	if err := ValidatePersonal("Tall-Elephant-42", "al@example.com", "Al"); err != nil {
+		t.Errorf("short names should not reject unrelated passwords, got %v", err)
+	}
+}
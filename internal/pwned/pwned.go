@@ -0,0 +1,97 @@
+package pwned
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+)
+
+const (
+	rangeURL       = "https://api.pwnedpasswords.com/range/"
+	defaultTimeout = 3 * time.Second
+)
+
+// Checker reports whether a password appears in a breach corpus
+type Checker interface {
+	Pwned(ctx context.Context, password string) (bool, error)
+}
+
+// Disabled is the default checker; it never reports a password as pwned
+type Disabled struct{}
+
+func (Disabled) Pwned(context.Context, string) (bool, error) {
+	return false, nil
+}
+
+// RangeChecker queries the Have I Been Pwned range API using k-anonymity:
+// only the first five hex characters of the SHA-1 ever leave the process
+type RangeChecker struct {
+	client *http.Client
+}
+
+// NewRangeChecker uses client when given, otherwise a client with a short
+// timeout so a slow API can't stall registration
+func NewRangeChecker(client *http.Client) *RangeChecker {
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	return &RangeChecker{client: client}
+}
+
+func (c *RangeChecker) Pwned(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rangeURL+prefix, nil)
+	if err != nil {
+		return false, fmt.Errorf("building range request: %w", err)
+	}
+	// padding hides the real number of matches from anyone watching the wire
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("querying range api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("range api returned %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		// padding rows carry a count of zero
+		if ok && count != "0" && strings.EqualFold(candidate, suffix) {
+			return true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("reading range response: %w", err)
+	}
+	return false, nil
+}
+
+// Validate rejects breached passwords but fails open when the checker errors,
+// so an outage of the range api never blocks registration
+func Validate(ctx context.Context, checker Checker, password string) *apperror.Error {
+	pwned, err := checker.Pwned(ctx, password)
+	if err != nil {
+		slog.Warn("breached password check unavailable", "error", err)
+		return nil
+	}
+	if pwned {
+		return apperror.Validation("password", "This password has appeared in a data breach, choose a different one")
+	}
+	return nil
+}
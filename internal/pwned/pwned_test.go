@@ -0,0 +1,69 @@
+package pwned
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// sha1("password") = 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+const passwordSuffix = "1E4C9B93F3F0682250B6CF8331B7EE68FD8"
+
+func stubChecker(t *testing.T, body string) *RangeChecker {
+	t.Helper()
+	return NewRangeChecker(&http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path != "/range/5BAA6" {
+			t.Errorf("expected only the 5 character prefix in the path, got %q", r.URL.Path)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	})})
+}
+
+func TestPwnedMatchedSuffix(t *testing.T) {
+	checker := stubChecker(t, "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n"+passwordSuffix+":9545824\r\n")
+
+	pwned, err := checker.Pwned(context.Background(), "password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !pwned {
+		t.Error("expected matched suffix to be reported as pwned")
+	}
+}
+
+func TestPwnedUnmatchedSuffix(t *testing.T) {
+	// a padding row for the same suffix has a zero count and must not match
+	checker := stubChecker(t, "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n"+passwordSuffix+":0\r\n")
+
+	pwned, err := checker.Pwned(context.Background(), "password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pwned {
+		t.Error("expected unmatched suffix to be reported as safe")
+	}
+}
+
+type failingChecker struct{}
+
+func (failingChecker) Pwned(context.Context, string) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestValidateFailsOpen(t *testing.T) {
+	if err := Validate(context.Background(), failingChecker{}, "password"); err != nil {
+		t.Errorf("expected an unavailable checker not to block, got %v", err)
+	}
+	if err := Validate(context.Background(), stubChecker(t, passwordSuffix+":3"), "password"); err == nil {
+		t.Error("expected a breached password to be rejected")
+	}
+}
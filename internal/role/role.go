@@ -0,0 +1,23 @@
+// Package role ranks household roles so checks can say "member or above"
+package role
+
+const (
+	Admin     = "admin"
+	Member    = "member"
+	Caregiver = "caregiver"
+	ReadOnly  = "readonly"
+)
+
+var ranks = map[string]int{
+	ReadOnly:  1,
+	Caregiver: 2,
+	Member:    3,
+	Admin:     4,
+}
+
+// AtLeast reports whether role ranks at or above minimum. unknown roles
+// rank below everything, so a typo never grants access
+func AtLeast(role, minimum string) bool {
+	rank, ok := ranks[role]
+	return ok && rank >= ranks[minimum]
+}
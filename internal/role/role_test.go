@@ -0,0 +1,25 @@
+package role
+
+import "testing"
+
+func TestAtLeastMember(t *testing.T) {
+	tests := map[string]bool{
+		Admin:     true,
+		Member:    true,
+		Caregiver: false,
+		ReadOnly:  false,
+		"":        false,
+		"owner":   false,
+	}
+	for r, want := range tests {
+		if got := AtLeast(r, Member); got != want {
+			t.Errorf("AtLeast(%q, member) = %v, want %v", r, got, want)
+		}
+	}
+}
+
+func TestAtLeastUnknownMinimum(t *testing.T) {
+	if AtLeast("owner", "owner") {
+		t.Error("an unknown role should not satisfy itself")
+	}
+}
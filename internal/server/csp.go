@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/logsafe"
+)
+
+const (
+	cspReportMaxBytes = 16 << 10
+	// one page load can trigger a burst of reports, and the endpoint takes
+	// anonymous posts, so anything past this per minute is dropped
+	cspReportsPerMinute = 60
+)
+
+// cspReport is the body browsers post to a report-uri
+type cspReport struct {
+	Report struct {
+		DocumentURI       string `json:"document-uri"`
+		ViolatedDirective string `json:"violated-directive"`
+		BlockedURI        string `json:"blocked-uri"`
+		SourceFile        string `json:"source-file"`
+		LineNumber        int    `json:"line-number"`
+	} `json:"csp-report"`
+}
+
+// reportLimiter allows a fixed number of reports per one-minute window
+type reportLimiter struct {
+	mu          sync.Mutex
+	windowStart time.Time
+	count       int
+}
+
+func (l *reportLimiter) allow(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.windowStart) >= time.Minute {
+		l.windowStart, l.count = now, 0
+	}
+	if l.count >= cspReportsPerMinute {
+		return false
+	}
+	l.count++
+	return true
+}
+
+// handleCSPReport logs violation reports from report-only mode. urls are
+// redacted since the page that violated the policy may carry a token
+func handleCSPReport(limiter *reportLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !limiter.allow(time.Now()) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		var report cspReport
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, cspReportMaxBytes)).Decode(&report); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		slog.Warn("csp violation",
+			"document_uri", logsafe.Redact(report.Report.DocumentURI),
+			"violated_directive", report.Report.ViolatedDirective,
+			"blocked_uri", logsafe.Redact(report.Report.BlockedURI),
+			"source_file", logsafe.Redact(report.Report.SourceFile),
+			"line", report.Report.LineNumber,
+		)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/shelterkin/shelterkin/components"
+	"github.com/shelterkin/shelterkin/internal/httpx"
+)
+
+var fallbackMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// routeFallback renders styled 404 and 405 responses in place of the mux's
+// plaintext defaults
+func routeFallback(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		if allowed := allowedMethods(mux, r); len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			renderRouteError(w, r, http.StatusMethodNotAllowed, "Method not allowed", "This page can't be used that way.")
+			return
+		}
+
+		renderRouteError(w, r, http.StatusNotFound, "Page not found", "The page you were looking for doesn't exist.")
+	})
+}
+
+func allowedMethods(mux *http.ServeMux, r *http.Request) []string {
+	var allowed []string
+	for _, method := range fallbackMethods {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+		if _, pattern := mux.Handler(probe); pattern != "" {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+// renderRouteError sends a full page, an alert fragment for HTMX requests, or
+// the same {"error": ...} body apperror.WriteJSON uses for api clients
+func renderRouteError(w http.ResponseWriter, r *http.Request, status int, title, message string) {
+	mode := httpx.Negotiate(r)
+	if mode == httpx.JSON {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	components.ErrorContent(mode == httpx.HTMX, title, message).Render(r.Context(), w)
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/apperror"
+	"github.com/shelterkin/shelterkin/internal/buildinfo"
+	"github.com/shelterkin/shelterkin/internal/middleware"
+)
+
+const readinessTimeout = 2 * time.Second
+
+// handleLivez reports that the process is up without touching dependencies
+func handleLivez(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// handleVersion reports which build is deployed
+func handleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(buildinfo.Get())
+}
+
+// handleReadyz reports whether both database handles are reachable
+func handleReadyz(db, readDB *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		for _, handle := range []*sql.DB{db, readDB} {
+			if err := handle.PingContext(ctx); err != nil {
+				slog.Error("readiness check failed", "error", err, "request_id", middleware.GetRequestID(r.Context()))
+				apperror.WriteJSON(w, apperror.Unavailable("database unreachable"))
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	}
+}
@@ -5,73 +5,177 @@ import (
 	"database/sql"
 	"fmt"
 	"io/fs"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/shelterkin/shelterkin/internal/config"
 	"github.com/shelterkin/shelterkin/internal/crypto"
 	"github.com/shelterkin/shelterkin/internal/middleware"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 type Server struct {
 	cfg        *config.Config
 	db         *sql.DB
+	readDB     *sql.DB
 	enc        *crypto.Encryptor
 	hmac       *crypto.HMACHasher
 	httpServer *http.Server
 	router     *http.ServeMux
+	autocert   *autocert.Manager
+	inFlight   *middleware.InFlight
 }
 
-func New(cfg *config.Config, db *sql.DB, enc *crypto.Encryptor, hmac *crypto.HMACHasher, staticFS fs.FS) *Server {
+// how often Shutdown logs the requests it is still waiting on
+const shutdownReportInterval = 5 * time.Second
+
+// New takes the single-writer handle and the pooled read-only handle from
+// database.OpenPair; read-only work should go through readDB
+func New(cfg *config.Config, db, readDB *sql.DB, enc *crypto.Encryptor, hmac *crypto.HMACHasher, staticFS fs.FS) *Server {
 	mux := http.NewServeMux()
 
-	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(staticFS)))
+	mux.Handle("GET /static/", http.StripPrefix("/static/", staticHandler(staticFS)))
+	mux.HandleFunc("GET /favicon.ico", rootFileHandler(staticFS, "favicon.ico", "image/x-icon"))
+	mux.HandleFunc("GET /site.webmanifest", rootFileHandler(staticFS, "site.webmanifest", "application/manifest+json"))
 
-	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("ok"))
-	})
+	mux.HandleFunc("GET /livez", handleLivez)
+	mux.HandleFunc("GET /version", handleVersion)
+	mux.HandleFunc("GET /readyz", handleReadyz(db, readDB))
+	// kept as an alias of /readyz for existing probes
+	mux.HandleFunc("GET /health", handleReadyz(db, readDB))
+
+	// only report-only mode asks browsers to post here
+	if cfg.CSPReportOnly {
+		mux.HandleFunc("POST "+middleware.CSPReportPath, handleCSPReport(&reportLimiter{}))
+	}
 
 	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		w.Write([]byte(`<!DOCTYPE html>
+		fmt.Fprintf(w, `<!DOCTYPE html>
 <html lang="en" data-theme="light">
-<head><meta charset="UTF-8"><meta name="viewport" content="width=device-width, initial-scale=1.0"><title>Shelterkin</title></head>
-<body style="font-family: system-ui, sans-serif; display: flex; align-items: center; justify-content: center; min-height: 100vh; margin: 0;">
-<div style="text-align: center;"><h1>Shelterkin</h1><p>Server is running.</p></div>
+<head><meta charset="UTF-8"><meta name="viewport" content="width=device-width, initial-scale=1.0"><title>Shelterkin</title>
+<style nonce="%s">body { font-family: system-ui, sans-serif; display: flex; align-items: center; justify-content: center; min-height: 100vh; margin: 0; } div { text-align: center; }</style></head>
+<body>
+<div><h1>Shelterkin</h1><p>Server is running.</p></div>
 </body>
-</html>`))
+</html>`, middleware.CSPNonce(r.Context()))
 	})
 
 	// middleware chain: outermost wraps first
-	var handler http.Handler = mux
-	handler = middleware.Logging(handler)
-	handler = middleware.SecurityHeaders(handler)
+	inFlight := middleware.NewInFlight()
+
+	var handler http.Handler = routeFallback(mux)
+	handler = middleware.Timeout(cfg.RequestTimeout)(handler)
+	handler = inFlight.Middleware(handler)
+	// probes usually address the server by its ip rather than BASE_URL
+	handler = middleware.AllowedHosts(append([]string{cfg.BaseHost}, cfg.AllowedHosts...), "/livez", "/readyz", "/health")(handler)
+	handler = middleware.Logging(cfg.LogQueryStrings)(handler)
+	handler = middleware.SecurityHeaders(cfg.CSPReportOnly)(handler)
+	handler = middleware.ClientIP(cfg.TrustedProxies)(handler)
 	handler = middleware.RequestID(handler)
 	handler = middleware.Recover(handler)
 
+	// a short header deadline frees connections from clients that dribble
+	// headers well before the full ReadTimeout
+	_, addr := cfg.Listener()
 	httpServer := &http.Server{
-		Addr:         fmt.Sprintf(":%d", cfg.Port),
-		Handler:      handler,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	var certManager *autocert.Manager
+	if len(cfg.TLSAutocertDomains) > 0 {
+		certManager = &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.TLSAutocertDomains...),
+			Cache:      autocert.DirCache(filepath.Join(cfg.DataDir, "autocert")),
+		}
+		httpServer.TLSConfig = certManager.TLSConfig()
 	}
 
 	return &Server{
 		cfg:        cfg,
 		db:         db,
+		readDB:     readDB,
 		enc:        enc,
 		hmac:       hmac,
 		httpServer: httpServer,
 		router:     mux,
+		autocert:   certManager,
+		inFlight:   inFlight,
 	}
 }
 
 func (s *Server) Start() error {
-	return s.httpServer.ListenAndServe()
+	ln, err := s.listen()
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
 }
 
+// listen binds the configured address. a socket file left by an unclean exit
+// would fail the bind, so one nothing is accepting on is removed first
+func (s *Server) listen() (net.Listener, error) {
+	network, addr := s.cfg.Listener()
+	if network == "unix" {
+		if info, err := os.Lstat(addr); err == nil && info.Mode()&fs.ModeSocket != 0 {
+			if conn, err := net.Dial("unix", addr); err == nil {
+				conn.Close()
+				return nil, fmt.Errorf("socket %s is already in use", addr)
+			}
+			if err := os.Remove(addr); err != nil {
+				return nil, fmt.Errorf("removing stale socket: %w", err)
+			}
+		}
+	}
+	return net.Listen(network, addr)
+}
+
+// Serve accepts connections on ln, over TLS when certificates or autocert
+// domains are configured
+func (s *Server) Serve(ln net.Listener) error {
+	switch {
+	case s.autocert != nil:
+		return s.httpServer.ServeTLS(ln, "", "")
+	case s.cfg.TLSCertFile != "":
+		return s.httpServer.ServeTLS(ln, s.cfg.TLSCertFile, s.cfg.TLSKeyFile)
+	default:
+		return s.httpServer.Serve(ln)
+	}
+}
+
+// Shutdown drains in-flight requests until they finish or ctx expires,
+// logging progress so a hung deploy shows what it is waiting on
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.httpServer.Shutdown(ctx)
+	}()
+
+	ticker := time.NewTicker(shutdownReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				slog.Warn("shutdown deadline reached with requests in flight",
+					"in_flight", s.inFlight.Count(),
+					"routes", s.inFlight.Routes(),
+				)
+			}
+			return err
+		case <-ticker.C:
+			slog.Info("waiting for in-flight requests to drain", "in_flight", s.inFlight.Count())
+		}
+	}
 }
@@ -0,0 +1,578 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/buildinfo"
+	"github.com/shelterkin/shelterkin/internal/config"
+	"github.com/shelterkin/shelterkin/internal/testutil"
+	"github.com/shelterkin/shelterkin/static"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		Port:    8080,
+		DataDir: "data",
+		// httptest requests are addressed to example.com
+		BaseURL:           "http://example.com",
+		BaseHost:          "example.com",
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
+
+// newTestServer uses db for both the write and read handles
+func newTestServer(t *testing.T, cfg *config.Config, db *sql.DB) *Server {
+	t.Helper()
+	return New(cfg, db, db, testutil.NewTestEncryptor(t), testutil.NewTestHMAC(t), static.FS)
+}
+
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshaling key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("writing cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("writing key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+func TestServeUsesTLSWithCertFiles(t *testing.T) {
+	cfg := testConfig()
+	cfg.TLSCertFile, cfg.TLSKeyFile = writeSelfSignedCert(t)
+
+	srv := newTestServer(t, cfg, testutil.NewTestDB(t))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.httpServer.Close() })
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("expected TLS handshake to succeed: %v", err)
+	}
+	conn.Close()
+}
+
+func TestServeUsesPlainHTTPWithoutTLSConfig(t *testing.T) {
+	srv := newTestServer(t, testConfig(), testutil.NewTestDB(t))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.httpServer.Close() })
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/health")
+	if err != nil {
+		t.Fatalf("GET /health: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestListenOnConfiguredHost(t *testing.T) {
+	cfg := testConfig()
+	cfg.ListenAddr = "127.0.0.1"
+	cfg.Port = 0
+
+	srv := newTestServer(t, cfg, testutil.NewTestDB(t))
+	if srv.httpServer.Addr != "127.0.0.1:0" {
+		t.Errorf("expected server addr 127.0.0.1:0, got %q", srv.httpServer.Addr)
+	}
+
+	ln, err := srv.listen()
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	defer ln.Close()
+
+	addr, ok := ln.Addr().(*net.TCPAddr)
+	if !ok || !addr.IP.Equal(net.ParseIP("127.0.0.1")) {
+		t.Errorf("expected a tcp listener on 127.0.0.1, got %s %s", ln.Addr().Network(), ln.Addr())
+	}
+}
+
+func TestListenOnUnixSocket(t *testing.T) {
+	cfg := testConfig()
+	cfg.ListenAddr = filepath.Join(t.TempDir(), "http.sock")
+
+	srv := newTestServer(t, cfg, testutil.NewTestDB(t))
+
+	// a socket file left behind by a crashed process must not block startup
+	stale, err := net.Listen("unix", cfg.ListenAddr)
+	if err != nil {
+		t.Fatalf("creating stale socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := srv.listen()
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	if ln.Addr().Network() != "unix" {
+		t.Fatalf("expected a unix listener, got %s", ln.Addr().Network())
+	}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.httpServer.Close() })
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", cfg.ListenAddr)
+		},
+	}}
+	resp, err := client.Get("http://shelterkin/livez")
+	if err != nil {
+		t.Fatalf("GET /livez over the socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+
+	if _, err := srv.listen(); err == nil {
+		t.Error("expected a socket that is accepting connections to be left alone")
+	}
+}
+
+func TestReadyzWithLiveDB(t *testing.T) {
+	srv := newTestServer(t, testConfig(), testutil.NewTestDB(t))
+
+	for _, path := range []string{"/readyz", "/health", "/livez"} {
+		rec := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestReadyzWithClosedDB(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	srv := newTestServer(t, testConfig(), db)
+	db.Close()
+
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected JSON reason, got content type %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "database unreachable") {
+		t.Errorf("expected reason in body, got %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest("GET", "/livez", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected /livez to stay 200 with a closed DB, got %d", rec.Code)
+	}
+}
+
+func TestVersionReportsInjectedVersion(t *testing.T) {
+	original := buildinfo.Version
+	buildinfo.Version = "v1.2.3-test"
+	t.Cleanup(func() { buildinfo.Version = original })
+
+	srv := newTestServer(t, testConfig(), testutil.NewTestDB(t))
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest("GET", "/version", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var info buildinfo.Info
+	if err := json.NewDecoder(rec.Body).Decode(&info); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if info.Version != "v1.2.3-test" {
+		t.Errorf("expected injected version, got %q", info.Version)
+	}
+	if info.GoVersion == "" {
+		t.Error("expected a go version")
+	}
+}
+
+func startWithSlowRoute(t *testing.T) (*Server, chan struct{}) {
+	t.Helper()
+
+	// the request goes to a loopback listener rather than example.com
+	cfg := testConfig()
+	cfg.BaseURL, cfg.BaseHost = "http://localhost:8080", "localhost:8080"
+	srv := newTestServer(t, cfg, testutil.NewTestDB(t))
+	release := make(chan struct{})
+	srv.router.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	go srv.Serve(ln)
+
+	go http.Get("http://" + ln.Addr().String() + "/slow")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for srv.inFlight.Count() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("slow request never became active")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return srv, release
+}
+
+func TestShutdownWaitsForInFlightRequests(t *testing.T) {
+	srv, release := startWithSlowRoute(t)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Shutdown(context.Background())
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("shutdown returned before the request finished: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected shutdown error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("shutdown did not return after the request finished")
+	}
+	if n := srv.inFlight.Count(); n != 0 {
+		t.Errorf("expected no requests in flight, got %d", n)
+	}
+}
+
+func TestShutdownReportsActiveRoutesAtDeadline(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	srv, release := startWithSlowRoute(t)
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, `"in_flight":1`) {
+		t.Errorf("expected in-flight count in log, got %s", out)
+	}
+	if !strings.Contains(out, "GET /slow") {
+		t.Errorf("expected active route in log, got %s", out)
+	}
+}
+
+func TestStaticETagConditionalRequests(t *testing.T) {
+	srv := newTestServer(t, testConfig(), testutil.NewTestDB(t))
+	handler := srv.httpServer.Handler
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/static/js/htmx.min.js", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag header")
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("expected unversioned asset to revalidate, got %q", cc)
+	}
+
+	req := httptest.NewRequest("GET", "/static/js/htmx.min.js", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for matching ETag, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/static/js/htmx.min.js", nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for mismatched ETag, got %d", rec.Code)
+	}
+	if rec.Body.Len() == 0 {
+		t.Error("expected body for mismatched ETag")
+	}
+}
+
+func TestStaticFingerprintedAssetIsImmutable(t *testing.T) {
+	srv := newTestServer(t, testConfig(), testutil.NewTestDB(t))
+	handler := srv.httpServer.Handler
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/static/js/htmx.min.js", nil))
+	hash := strings.Trim(rec.Header().Get("ETag"), `"`)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/static/js/htmx.min.js?v="+hash, nil))
+	if cc := rec.Header().Get("Cache-Control"); cc != immutableCacheControl {
+		t.Errorf("expected immutable cache control, got %q", cc)
+	}
+}
+
+func TestFaviconAndManifestServedFromRoot(t *testing.T) {
+	srv := newTestServer(t, testConfig(), testutil.NewTestDB(t))
+
+	tests := []struct {
+		path        string
+		contentType string
+	}{
+		{"/favicon.ico", "image/"},
+		{"/site.webmanifest", "application/manifest+json"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", tt.path, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+			t.Errorf("%s: expected content type %s, got %q", tt.path, tt.contentType, ct)
+		}
+		if cc := rec.Header().Get("Cache-Control"); cc != rootFileCacheControl {
+			t.Errorf("%s: expected cache control %q, got %q", tt.path, rootFileCacheControl, cc)
+		}
+	}
+}
+
+func TestUnknownPathRendersStyledNotFound(t *testing.T) {
+	srv := newTestServer(t, testConfig(), testutil.NewTestDB(t))
+
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest("GET", "/no-such-page", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("expected html, got %q", ct)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "<html") || !strings.Contains(body, "Page not found") {
+		t.Errorf("expected full 404 page, got %s", body)
+	}
+
+	// the layout's inline script carries the nonce the CSP allows
+	csp := rec.Header().Get("Content-Security-Policy")
+	start := strings.Index(csp, "'nonce-") + len("'nonce-")
+	nonce := csp[start : start+strings.Index(csp[start:], "'")]
+	if !strings.Contains(body, `<script nonce="`+nonce+`">`) {
+		t.Errorf("expected inline script to carry nonce %q, got %s", nonce, body)
+	}
+}
+
+func TestUnknownPathHTMXGetsFragment(t *testing.T) {
+	srv := newTestServer(t, testConfig(), testutil.NewTestDB(t))
+
+	req := httptest.NewRequest("GET", "/no-such-page", nil)
+	req.Header.Set("HX-Request", "true")
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "<html") {
+		t.Errorf("expected fragment without layout, got %s", body)
+	}
+	if !strings.Contains(body, `role="alert"`) {
+		t.Errorf("expected alert fragment, got %s", body)
+	}
+}
+
+func TestUnknownPathJSONClientGetsJSON(t *testing.T) {
+	srv := newTestServer(t, testConfig(), testutil.NewTestDB(t))
+
+	req := httptest.NewRequest("GET", "/no-such-page", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected json content type, got %q", ct)
+	}
+	var body map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body["error"] == "" {
+		t.Errorf("expected an error message in json, got %v (%v)", body, err)
+	}
+}
+
+func TestCSPReportIsLogged(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	cfg := testConfig()
+	cfg.CSPReportOnly = true
+	srv := newTestServer(t, cfg, testutil.NewTestDB(t))
+
+	payload := `{"csp-report": {"document-uri": "https://example.com/invite?token=abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG",
+		"violated-directive": "script-src-elem", "blocked-uri": "https://cdn.example.net/x.js", "line-number": 12}}`
+	req := httptest.NewRequest("POST", "/csp-report", strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/csp-report")
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `"msg":"csp violation"`) || !strings.Contains(out, `"violated_directive":"script-src-elem"`) {
+		t.Errorf("expected the violation in the log, got %s", out)
+	}
+	if strings.Contains(out, "abcdefghijklmnopqrstuvwxyz") {
+		t.Errorf("expected the token in the document uri to be redacted, got %s", out)
+	}
+	if csp := rec.Header().Get("Content-Security-Policy-Report-Only"); csp == "" {
+		t.Error("expected the report-only policy header")
+	}
+}
+
+func TestCSPReportRateLimited(t *testing.T) {
+	limiter := &reportLimiter{}
+	now := time.Now()
+	for range cspReportsPerMinute {
+		if !limiter.allow(now) {
+			t.Fatal("expected reports under the limit to be allowed")
+		}
+	}
+	if limiter.allow(now.Add(30 * time.Second)) {
+		t.Error("expected a report over the limit to be dropped")
+	}
+	if !limiter.allow(now.Add(time.Minute)) {
+		t.Error("expected the limit to reset after a minute")
+	}
+}
+
+func TestCSPReportRouteOnlyInReportOnlyMode(t *testing.T) {
+	srv := newTestServer(t, testConfig(), testutil.NewTestDB(t))
+
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest("POST", "/csp-report", strings.NewReader("{}")))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when the policy is enforced, got %d", rec.Code)
+	}
+}
+
+func TestWrongMethodRendersMethodNotAllowed(t *testing.T) {
+	srv := newTestServer(t, testConfig(), testutil.NewTestDB(t))
+
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest("POST", "/livez", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rec.Code)
+	}
+	if allow := rec.Header().Get("Allow"); allow != "GET, HEAD" {
+		t.Errorf("expected Allow: GET, HEAD, got %q", allow)
+	}
+	if !strings.Contains(rec.Body.String(), "Method not allowed") {
+		t.Errorf("expected styled 405 page, got %s", rec.Body.String())
+	}
+}
+
+func TestReadHeaderTimeoutSet(t *testing.T) {
+	srv := newTestServer(t, testConfig(), testutil.NewTestDB(t))
+	if srv.httpServer.ReadHeaderTimeout != 5*time.Second {
+		t.Errorf("expected ReadHeaderTimeout 5s, got %v", srv.httpServer.ReadHeaderTimeout)
+	}
+}
+
+func TestSpoofedHostRejected(t *testing.T) {
+	srv := newTestServer(t, testConfig(), testutil.NewTestDB(t))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "evil.example.net"
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a spoofed host, got %d", rec.Code)
+	}
+
+	// probes reach the server by ip
+	req = httptest.NewRequest("GET", "/livez", nil)
+	req.Host = "10.0.0.5:8080"
+	rec = httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected probes to skip the host check, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// root files can't carry a ?v= fingerprint, so they get a day instead of a year
+const rootFileCacheControl = "public, max-age=86400"
+
+// staticHandler serves assets with a content-hash ETag computed once at startup.
+// a request whose ?v= matches the hash is fingerprinted and cached as immutable;
+// everything else must revalidate and gets a 304 when the ETag still matches
+func staticHandler(fsys fs.FS) http.Handler {
+	hashes := make(map[string]string)
+	fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			slog.Warn("hashing static asset", "path", path, "error", err)
+			return nil
+		}
+		sum := sha256.Sum256(data)
+		hashes[path] = hex.EncodeToString(sum[:8])
+		return nil
+	})
+
+	files := http.FileServerFS(fsys)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hash, ok := hashes[strings.TrimPrefix(r.URL.Path, "/")]; ok {
+			// FileServer compares If-None-Match against this header
+			w.Header().Set("ETag", `"`+hash+`"`)
+			if r.URL.Query().Get("v") == hash {
+				w.Header().Set("Cache-Control", immutableCacheControl)
+			} else {
+				w.Header().Set("Cache-Control", "no-cache")
+			}
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// rootFileHandler serves a file browsers request from a fixed path at the site
+// root, like /favicon.ico, with an explicit type since mime tables disagree
+func rootFileHandler(fsys fs.FS, name, contentType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", rootFileCacheControl)
+		http.ServeFileFS(w, r, fsys, name)
+	}
+}
@@ -17,6 +17,9 @@ func NewTestDB(t *testing.T) *sql.DB {
 		t.Fatalf("opening test database: %v", err)
 	}
 
+	// every connection to :memory: is a separate database
+	sqlDB.SetMaxOpenConns(1)
+
 	if _, err := sqlDB.Exec("PRAGMA foreign_keys = ON"); err != nil {
 		sqlDB.Close()
 		t.Fatalf("enabling foreign keys: %v", err)
@@ -1,9 +1,200 @@
 package testutil
 
-// factory functions for creating test data will be added here
-// after sqlc generates the query types in internal/db/dbgen/
-//
-// planned functions:
-// - CreateTestHousehold(t, db, ...options)
-// - CreateTestUser(t, db, household, ...options)
-// - CreateTestCareRecipient(t, db, household, ...options)
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/crypto"
+	"github.com/shelterkin/shelterkin/internal/database"
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+	"github.com/shelterkin/shelterkin/internal/ulid"
+)
+
+// factories encrypt and hash with the fixed test key, so services built from
+// NewTestEncryptor and NewTestHMAC can read what they create
+
+func CreateTestHousehold(t *testing.T, db *sql.DB) dbgen.Household {
+	t.Helper()
+
+	nameEnc := mustEncrypt(t, "Test Household")
+	household, err := dbgen.New(db).CreateHousehold(context.Background(), dbgen.CreateHouseholdParams{
+		ID:                 ulid.New(),
+		NameEnc:            nameEnc,
+		EncryptionSalt:     "test-salt",
+		OnboardingProgress: `{"step":"profile"}`,
+		Settings:           "{}",
+	})
+	if err != nil {
+		t.Fatalf("creating test household: %v", err)
+	}
+	return household
+}
+
+type userOptions struct {
+	email       string
+	displayName string
+	role        string
+}
+
+type UserOption func(*userOptions)
+
+func WithEmail(email string) UserOption {
+	return func(o *userOptions) { o.email = email }
+}
+
+func WithDisplayName(name string) UserOption {
+	return func(o *userOptions) { o.displayName = name }
+}
+
+func WithRole(role string) UserOption {
+	return func(o *userOptions) { o.role = role }
+}
+
+func CreateTestUser(t *testing.T, db *sql.DB, householdID string, opts ...UserOption) dbgen.User {
+	t.Helper()
+
+	id := ulid.New()
+	o := userOptions{email: id + "@example.com", displayName: "Test User", role: "member"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	user, err := dbgen.New(db).CreateUser(context.Background(), dbgen.CreateUserParams{
+		ID:             id,
+		HouseholdID:    householdID,
+		EmailEnc:       mustEncrypt(t, o.email),
+		EmailHash:      NewTestHMAC(t).Hash(o.email),
+		PasswordHash:   sql.NullString{String: "test-password-hash", Valid: true},
+		DisplayNameEnc: mustEncrypt(t, o.displayName),
+		Role:           o.role,
+		AuthProvider:   "local",
+		Timezone:       "UTC",
+	})
+	if err != nil {
+		t.Fatalf("creating test user: %v", err)
+	}
+	return user
+}
+
+type SessionOption func(*dbgen.CreateSessionParams)
+
+func WithSessionExpiry(expiresAt time.Time) SessionOption {
+	return func(p *dbgen.CreateSessionParams) { p.ExpiresAt = expiresAt.UTC().Format(database.TimestampFormat) }
+}
+
+func WithSessionIP(ip string) SessionOption {
+	return func(p *dbgen.CreateSessionParams) { p.IpAddress = sql.NullString{String: ip, Valid: true} }
+}
+
+func CreateTestSession(t *testing.T, db *sql.DB, userID, householdID string, opts ...SessionOption) dbgen.Session {
+	t.Helper()
+
+	params := dbgen.CreateSessionParams{
+		ID:          ulid.New(),
+		UserID:      userID,
+		HouseholdID: householdID,
+		ExpiresAt:   time.Now().UTC().Add(24 * time.Hour).Format(database.TimestampFormat),
+	}
+	for _, opt := range opts {
+		opt(&params)
+	}
+
+	session, err := dbgen.New(db).CreateSession(context.Background(), params)
+	if err != nil {
+		t.Fatalf("creating test session: %v", err)
+	}
+	return session
+}
+
+type InviteOption func(*dbgen.CreateInviteParams)
+
+func WithInviteRole(role string) InviteOption {
+	return func(p *dbgen.CreateInviteParams) { p.Role = role }
+}
+
+func WithInviteExpiry(expiresAt time.Time) InviteOption {
+	return func(p *dbgen.CreateInviteParams) { p.ExpiresAt = expiresAt.UTC().Format(database.TimestampFormat) }
+}
+
+// CreateTestInvite returns the invite and the plaintext token for it
+func CreateTestInvite(t *testing.T, db *sql.DB, householdID, invitedBy string, opts ...InviteOption) (dbgen.Invite, string) {
+	t.Helper()
+
+	token, err := crypto.GenerateToken()
+	if err != nil {
+		t.Fatalf("generating invite token: %v", err)
+	}
+
+	params := dbgen.CreateInviteParams{
+		ID:          ulid.New(),
+		HouseholdID: householdID,
+		InvitedBy:   invitedBy,
+		TokenHash:   NewTestHMAC(t).Hash(token),
+		Role:        "member",
+		ExpiresAt:   time.Now().UTC().Add(24 * time.Hour).Format(database.TimestampFormat),
+		MaxUses:     1,
+	}
+	for _, opt := range opts {
+		opt(&params)
+	}
+
+	invite, err := dbgen.New(db).CreateInvite(context.Background(), params)
+	if err != nil {
+		t.Fatalf("creating test invite: %v", err)
+	}
+	return invite, token
+}
+
+type LoginAttemptOption func(*dbgen.LoginAttempt)
+
+func WithAttemptSucceeded(succeeded bool) LoginAttemptOption {
+	return func(a *dbgen.LoginAttempt) {
+		a.Succeeded = 0
+		if succeeded {
+			a.Succeeded = 1
+		}
+	}
+}
+
+func WithAttemptIP(ip string) LoginAttemptOption {
+	return func(a *dbgen.LoginAttempt) { a.IpAddress = ip }
+}
+
+func WithAttemptedAt(at time.Time) LoginAttemptOption {
+	return func(a *dbgen.LoginAttempt) { a.AttemptedAt = at.UTC().Format(database.TimestampFormat) }
+}
+
+// CreateTestLoginAttempt inserts directly because CreateLoginAttempt always
+// stamps the current time
+func CreateTestLoginAttempt(t *testing.T, db *sql.DB, emailHash string, opts ...LoginAttemptOption) dbgen.LoginAttempt {
+	t.Helper()
+
+	attempt := dbgen.LoginAttempt{
+		ID:          ulid.New(),
+		EmailHash:   emailHash,
+		IpAddress:   "127.0.0.1",
+		AttemptedAt: time.Now().UTC().Format(database.TimestampFormat),
+	}
+	for _, opt := range opts {
+		opt(&attempt)
+	}
+
+	if _, err := db.Exec(
+		`INSERT INTO login_attempts (id, email_hash, ip_address, succeeded, attempted_at) VALUES (?, ?, ?, ?, ?)`,
+		attempt.ID, attempt.EmailHash, attempt.IpAddress, attempt.Succeeded, attempt.AttemptedAt,
+	); err != nil {
+		t.Fatalf("creating test login attempt: %v", err)
+	}
+	return attempt
+}
+
+func mustEncrypt(t *testing.T, plaintext string) string {
+	t.Helper()
+	encrypted, err := NewTestEncryptor(t).Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("encrypting test value: %v", err)
+	}
+	return encrypted
+}
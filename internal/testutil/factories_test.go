@@ -0,0 +1,67 @@
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/db/dbgen"
+)
+
+func TestFactoriesCreateScopedRows(t *testing.T) {
+	db := NewTestDB(t)
+	queries := dbgen.New(db)
+	ctx := context.Background()
+
+	household := CreateTestHousehold(t, db)
+	other := CreateTestHousehold(t, db)
+	user := CreateTestUser(t, db, household.ID, WithRole("admin"), WithEmail("ada@example.com"))
+
+	if _, err := queries.GetUserByID(ctx, dbgen.GetUserByIDParams{ID: user.ID, HouseholdID: household.ID}); err != nil {
+		t.Errorf("expected user in its household: %v", err)
+	}
+	if _, err := queries.GetUserByID(ctx, dbgen.GetUserByIDParams{ID: user.ID, HouseholdID: other.ID}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected user to be scoped to its household, got %v", err)
+	}
+	if email, _ := NewTestEncryptor(t).Decrypt(user.EmailEnc); email != "ada@example.com" {
+		t.Errorf("expected email to decrypt with the test key, got %q", email)
+	}
+
+	session := CreateTestSession(t, db, user.ID, household.ID, WithSessionIP("10.0.0.1"))
+	got, err := queries.GetSessionByID(ctx, session.ID)
+	if err != nil {
+		t.Fatalf("expected session to be retrievable: %v", err)
+	}
+	if got.HouseholdID != household.ID || got.IpAddress.String != "10.0.0.1" {
+		t.Errorf("unexpected session %+v", got)
+	}
+
+	expired := CreateTestSession(t, db, user.ID, household.ID, WithSessionExpiry(time.Now().Add(-time.Hour)))
+	if _, err := queries.GetSessionByID(ctx, expired.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected expired session to be filtered out, got %v", err)
+	}
+
+	invite, token := CreateTestInvite(t, db, household.ID, user.ID, WithInviteRole("admin"))
+	found, err := queries.GetInviteByToken(ctx, NewTestHMAC(t).Hash(token))
+	if err != nil {
+		t.Fatalf("expected invite to resolve by token: %v", err)
+	}
+	if found.ID != invite.ID || found.Role != "admin" {
+		t.Errorf("unexpected invite %+v", found)
+	}
+
+	attempt := CreateTestLoginAttempt(t, db, user.EmailHash, WithAttemptSucceeded(true), WithAttemptedAt(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+	var attemptedAt string
+	var succeeded int
+	if err := db.QueryRow("SELECT attempted_at, succeeded FROM login_attempts WHERE id = ?", attempt.ID).Scan(&attemptedAt, &succeeded); err != nil {
+		t.Fatalf("expected login attempt to be stored: %v", err)
+	}
+	if attemptedAt != "2025-01-01T00:00:00Z" {
+		t.Errorf("expected custom timestamp, got %q", attemptedAt)
+	}
+	if succeeded != 1 {
+		t.Errorf("expected a successful attempt, got succeeded=%d", succeeded)
+	}
+}
@@ -0,0 +1,29 @@
+package ulid
+
+import (
+	"crypto/rand"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+var (
+	mu sync.Mutex
+	// monotonic entropy keeps ids from the same millisecond strictly
+	// increasing, so ulid order always matches creation order
+	entropy = ulid.Monotonic(rand.Reader, 0)
+)
+
+// New returns a lexicographically sortable id for primary keys
+func New() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return ulid.MustNew(ulid.Timestamp(time.Now()), entropy).String()
+}
+
+// Valid reports whether id is a well-formed ulid
+func Valid(id string) bool {
+	_, err := ulid.ParseStrict(id)
+	return err == nil
+}
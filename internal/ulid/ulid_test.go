@@ -0,0 +1,14 @@
+package ulid
+
+import "testing"
+
+func TestNewIsStrictlyIncreasing(t *testing.T) {
+	prev := New()
+	for range 10000 {
+		id := New()
+		if id <= prev {
+			t.Fatalf("expected %s to sort after %s", id, prev)
+		}
+		prev = id
+	}
+}
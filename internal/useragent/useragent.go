@@ -0,0 +1,79 @@
+// Package useragent turns the raw user agent and ip stored on a session into
+// short labels for the sessions list, without a device or geoip database
+package useragent
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// rules are checked in order, since most browsers claim to be several others:
+// edge and opera also say Chrome, chrome also says Safari
+var browsers = []struct{ token, name string }{
+	{"Edg", "Edge"},
+	{"OPR/", "Opera"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+}
+
+var systems = []struct{ token, name string }{
+	{"iPhone", "iPhone"},
+	{"iPad", "iPad"},
+	{"Android", "Android"},
+	{"Windows", "Windows"},
+	{"CrOS", "ChromeOS"},
+	{"Mac OS X", "macOS"},
+	{"Linux", "Linux"},
+}
+
+// Label names the browser and platform, like "Firefox on Windows". anything
+// unrecognized degrades to whatever part was recognized
+func Label(ua string) string {
+	browser := match(ua, browsers)
+	system := match(ua, systems)
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return "Unknown browser on " + system
+	default:
+		return "Unknown device"
+	}
+}
+
+func match(ua string, rules []struct{ token, name string }) string {
+	for _, rule := range rules {
+		if strings.Contains(ua, rule.token) {
+			return rule.name
+		}
+	}
+	return ""
+}
+
+// Network describes an ip coarsely enough to recognize a session without
+// printing the full address: the first octet of v4, the first group of v6
+func Network(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "Unknown network"
+	}
+	addr = addr.Unmap()
+
+	switch {
+	case addr.IsLoopback():
+		return "This device"
+	case addr.IsPrivate() || addr.IsLinkLocalUnicast():
+		return "Local network"
+	case addr.Is4():
+		return "IPv4 " + strings.SplitN(addr.String(), ".", 2)[0] + ".x.x.x"
+	default:
+		return "IPv6 " + strings.SplitN(addr.StringExpanded(), ":", 2)[0] + ":…"
+	}
+}
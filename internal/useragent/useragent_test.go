@@ -0,0 +1,49 @@
+package useragent
+
+import "testing"
+
+func TestLabel(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want string
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:131.0) Gecko/20100101 Firefox/131.0", "Firefox on Windows"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Safari/537.36", "Chrome on Windows"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Safari/537.36 Edg/130.0.0.0", "Edge on Windows"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Safari/605.1.15", "Safari on macOS"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 18_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Mobile/15E148 Safari/604.1", "Safari on iPhone"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 18_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/130.0.6723.90 Mobile/15E148 Safari/604.1", "Chrome on iPhone"},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Mobile Safari/537.36", "Chrome on Android"},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0", "Firefox on Linux"},
+		{"curl/8.5.0", "curl"},
+		{"Mozilla/5.0 (X11; Linux x86_64)", "Unknown browser on Linux"},
+		{"", "Unknown device"},
+		{"garbage \x00 value", "Unknown device"},
+	}
+	for _, tt := range tests {
+		if got := Label(tt.ua); got != tt.want {
+			t.Errorf("Label(%q) = %q, want %q", tt.ua, got, tt.want)
+		}
+	}
+}
+
+func TestNetwork(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"203.0.113.7", "IPv4 203.x.x.x"},
+		{"::ffff:203.0.113.7", "IPv4 203.x.x.x"},
+		{"2001:db8::1", "IPv6 2001:…"},
+		{"127.0.0.1", "This device"},
+		{"192.168.1.20", "Local network"},
+		{"fd00::1", "Local network"},
+		{"", "Unknown network"},
+		{"not-an-ip", "Unknown network"},
+	}
+	for _, tt := range tests {
+		if got := Network(tt.ip); got != tt.want {
+			t.Errorf("Network(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
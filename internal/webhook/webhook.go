@@ -0,0 +1,153 @@
+// Package webhook delivers security events to an operator's endpoint, signed
+// so the receiver can check they came from this server
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/clock"
+	"github.com/shelterkin/shelterkin/internal/crypto"
+	"github.com/shelterkin/shelterkin/internal/ulid"
+)
+
+const (
+	EventLogin           = "login"
+	EventPasswordChanged = "password_changed"
+	EventRoleChanged     = "role_changed"
+	EventAccountLocked   = "account_locked"
+)
+
+const (
+	// SignatureHeader carries hex hmac-sha256 over "<timestamp>.<body>", keyed
+	// with the shared secret
+	SignatureHeader = "X-Shelterkin-Signature"
+	// TimestampHeader is unix seconds, signed with the body so a captured
+	// delivery can't be replayed later with a fresh timestamp
+	TimestampHeader = "X-Shelterkin-Timestamp"
+
+	queueSize       = 256
+	deliveryTimeout = 10 * time.Second
+)
+
+// Event carries ids only, never emails, names or addresses, since the
+// receiver sits outside the household's encryption
+type Event struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	HouseholdID string    `json:"household_id"`
+	UserID      string    `json:"user_id,omitempty"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+// Dispatcher queues events and delivers them one at a time from Run, retrying
+// failures. a nil Dispatcher discards events, so webhooks stay off unless one
+// is configured and callers never need to check
+type Dispatcher struct {
+	url     string
+	signer  *crypto.HMACHasher
+	clock   clock.Clock
+	client  *http.Client
+	events  chan Event
+	backoff []time.Duration
+}
+
+func NewDispatcher(url, secret string, clk clock.Clock) *Dispatcher {
+	return &Dispatcher{
+		url:     url,
+		signer:  crypto.NewHMAC([]byte(secret)),
+		clock:   clk,
+		client:  &http.Client{Timeout: deliveryTimeout},
+		events:  make(chan Event, queueSize),
+		backoff: []time.Duration{time.Second, 10 * time.Second, time.Minute},
+	}
+}
+
+// Send queues an event of the given type without blocking
+func (d *Dispatcher) Send(eventType, householdID, userID string) {
+	if d == nil {
+		return
+	}
+	ev := Event{ID: ulid.New(), Type: eventType, HouseholdID: householdID, UserID: userID, OccurredAt: d.clock.Now().UTC()}
+	select {
+	case d.events <- ev:
+	default:
+		deadLetter(ev, 0, fmt.Errorf("queue full"))
+	}
+}
+
+// Run delivers queued events until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		select {
+		case ev := <-d.events:
+			d.deliver(ctx, ev)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// deliver tries once plus once per backoff step. an event that never gets a
+// 2xx is logged as a dead letter with enough to replay it by hand
+func (d *Dispatcher) deliver(ctx context.Context, ev Event) {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		deadLetter(ev, 0, err)
+		return
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = d.post(ctx, body)
+		if err == nil {
+			return
+		}
+		if attempt == len(d.backoff) {
+			deadLetter(ev, attempt+1, err)
+			return
+		}
+		select {
+		case <-time.After(d.backoff[attempt]):
+		case <-ctx.Done():
+			deadLetter(ev, attempt+1, ctx.Err())
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(d.clock.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, d.signer.Hash(timestamp+"."+string(body)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func deadLetter(ev Event, attempts int, err error) {
+	slog.Error("webhook delivery failed, dropping event",
+		"event_id", ev.ID,
+		"event_type", ev.Type,
+		"household_id", ev.HouseholdID,
+		"attempts", attempts,
+		"error", err,
+	)
+}
@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shelterkin/shelterkin/internal/clock"
+	"github.com/shelterkin/shelterkin/internal/testutil"
+)
+
+const testSecret = "webhook-test-secret-0123456789abcdef"
+
+type delivery struct {
+	body      []byte
+	timestamp string
+	signature string
+}
+
+// stubReceiver answers with the given statuses in turn, then 200s
+func stubReceiver(t *testing.T, statuses ...int) (*httptest.Server, chan delivery, *atomic.Int32) {
+	t.Helper()
+	deliveries := make(chan delivery, 10)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		body, _ := io.ReadAll(r.Body)
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		deliveries <- delivery{body: body, timestamp: r.Header.Get(TimestampHeader), signature: r.Header.Get(SignatureHeader)}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, deliveries, &calls
+}
+
+func startDispatcher(t *testing.T, d *Dispatcher) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func receive(t *testing.T, deliveries chan delivery) delivery {
+	t.Helper()
+	select {
+	case got := <-deliveries:
+		return got
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a delivery")
+		return delivery{}
+	}
+}
+
+func TestLoginEventSignedDelivery(t *testing.T) {
+	srv, deliveries, _ := stubReceiver(t)
+	d := NewDispatcher(srv.URL, testSecret, clock.Real())
+	startDispatcher(t, d)
+
+	d.Send(EventLogin, "h1", "u1")
+	got := receive(t, deliveries)
+
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(got.timestamp + "." + string(got.body)))
+	if want := hex.EncodeToString(mac.Sum(nil)); got.signature != want {
+		t.Errorf("signature %q does not match the receiver's computation %q", got.signature, want)
+	}
+
+	var ev Event
+	if err := json.Unmarshal(got.body, &ev); err != nil {
+		t.Fatalf("decoding payload: %v", err)
+	}
+	if ev.Type != EventLogin || ev.HouseholdID != "h1" || ev.UserID != "u1" || ev.ID == "" || ev.OccurredAt.IsZero() {
+		t.Errorf("unexpected payload %+v", ev)
+	}
+}
+
+func TestDeliverySignsClockTime(t *testing.T) {
+	srv, deliveries, _ := stubReceiver(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	d := NewDispatcher(srv.URL, testSecret, testutil.NewFakeClock(now))
+	startDispatcher(t, d)
+
+	d.Send(EventPasswordChanged, "h1", "u1")
+	got := receive(t, deliveries)
+
+	if want := strconv.FormatInt(now.Unix(), 10); got.timestamp != want {
+		t.Errorf("expected timestamp %s, got %s", want, got.timestamp)
+	}
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(got.timestamp + "." + string(got.body)))
+	if want := hex.EncodeToString(mac.Sum(nil)); got.signature != want {
+		t.Errorf("signature %q does not cover the clock's timestamp, want %q", got.signature, want)
+	}
+	var ev Event
+	if err := json.Unmarshal(got.body, &ev); err != nil || !ev.OccurredAt.Equal(now) {
+		t.Errorf("expected the event to occur at %v, got %+v, %v", now, ev, err)
+	}
+}
+
+func TestDeliveryRetriesFailures(t *testing.T) {
+	srv, deliveries, calls := stubReceiver(t, http.StatusInternalServerError, http.StatusBadGateway)
+	d := NewDispatcher(srv.URL, testSecret, clock.Real())
+	d.backoff = []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}
+	startDispatcher(t, d)
+
+	d.Send(EventRoleChanged, "h1", "u1")
+	receive(t, deliveries)
+	if got := calls.Load(); got != 3 {
+		t.Errorf("expected two failures then a success, got %d calls", got)
+	}
+}
+
+func TestDeliveryGivesUpAfterBackoff(t *testing.T) {
+	srv, _, calls := stubReceiver(t, 500, 500, 500, 500, 500)
+	d := NewDispatcher(srv.URL, testSecret, clock.Real())
+	d.backoff = []time.Duration{time.Millisecond, time.Millisecond}
+
+	d.deliver(context.Background(), Event{ID: "e1", Type: EventAccountLocked, HouseholdID: "h1"})
+	if got := calls.Load(); got != 3 {
+		t.Errorf("expected one attempt per backoff step plus the first, got %d", got)
+	}
+}
+
+func TestNilDispatcherDiscards(t *testing.T) {
+	var d *Dispatcher
+	d.Send(EventLogin, "h1", "u1")
+}
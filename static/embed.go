@@ -2,5 +2,5 @@ package static
 
 import "embed"
 
-//go:embed all:css all:js
+//go:embed all:css all:js favicon.ico site.webmanifest
 var FS embed.FS